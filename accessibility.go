@@ -0,0 +1,83 @@
+package p4p
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A tagged figure, referencing its marked content on a page.
+type figure struct {
+	page int
+	mcid int
+	alt  string
+}
+
+// Sets the natural language of the document as a BCP 47 tag, e.g. "en-US", used by screen readers.
+func (g *Generator) SetLanguage(lang string) {
+	g.lang = lang
+}
+
+// Starts a marked content sequence for an image tagged as a figure with alternate text.
+func (g *Generator) beginFigure(alt string) {
+	page := g.pdf.PageNo()
+	mcid := 0
+	for _, f := range g.figures {
+		if f.page == page {
+			mcid++
+		}
+	}
+	g.figures = append(g.figures, figure{page: page, mcid: mcid, alt: alt})
+	g.pdf.RawWriteStr("/Figure <</MCID " + strconv.Itoa(mcid) + ">> BDC")
+}
+
+func (g *Generator) endFigure() {
+	g.pdf.RawWriteStr("EMC")
+}
+
+func (g *Generator) putLanguage(p *pdfFile) {
+	if g.lang != "" {
+		p.extendDict(p.root(), "/Lang "+pdfString(g.lang))
+	}
+}
+
+// Writes the structure tree making the document a tagged PDF.
+func (g *Generator) putStructTree(p *pdfFile) {
+	pages := p.pages()
+	var figures []figure
+	for _, f := range g.figures {
+		if f.page >= p.firstPage && f.page < p.firstPage+len(pages) {
+			figures = append(figures, f)
+		}
+	}
+	if len(figures) == 0 {
+		return
+	}
+	root := p.add("")
+	// Structure elements of each page's marked content, indexed by MCID.
+	parents := make(map[int][]string)
+	var kids []string
+	for _, f := range figures {
+		i := f.page - p.firstPage
+		elem := p.add("<</Type /StructElem /S /Figure /P " + ref(root) +
+			" /Pg " + ref(pages[i]) +
+			" /K " + strconv.Itoa(f.mcid) +
+			" /Alt " + pdfString(f.alt) + ">>")
+		kids = append(kids, ref(elem))
+		parents[i] = append(parents[i], ref(elem))
+	}
+	var nums []string
+	for i, page := range pages {
+		if elems, ok := parents[i]; ok {
+			p.extendDict(page, "/StructParents "+strconv.Itoa(i))
+			nums = append(nums, strconv.Itoa(i)+" ["+strings.Join(elems, " ")+"]")
+		}
+	}
+	parentTree := p.add("<</Nums [" + strings.Join(nums, " ") + "]>>")
+	p.set(root, "<</Type /StructTreeRoot /K ["+strings.Join(kids, " ")+"] /ParentTree "+ref(parentTree)+">>")
+	p.extendDict(p.root(), "/MarkInfo <</Marked true>>\n/StructTreeRoot "+ref(root))
+}
+
+// Returns an indirect reference to object n.
+func ref(n int) string {
+	return strconv.Itoa(n) + " 0 R"
+}
@@ -0,0 +1,33 @@
+package p4p_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestLanguageAndAltText(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetLanguage("en-US")
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Fit, AltText: "A gopher"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageFile("gophers/gopher2.png", p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	for _, s := range []string{"/Lang (en-US)", "/MarkInfo <</Marked true>>", "/StructTreeRoot ", "/S /Figure", "/Alt (A gopher)"} {
+		if !bytes.Contains(pdf, []byte(s)) {
+			t.Fatal("output is missing", s)
+		}
+	}
+	pages := pageContents(t, pdf)
+	if !strings.Contains(pages[0], "/Figure <</MCID 0>> BDC") {
+		t.Fatal("image on first page is not tagged")
+	}
+	if strings.Contains(pages[1], "BDC") {
+		t.Fatal("image without alt text is tagged")
+	}
+}
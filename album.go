@@ -0,0 +1,39 @@
+package p4p
+
+import (
+	"image"
+)
+
+// Adds images as an album: portrait images are paired side by side on one page, while all other images
+// get a page of their own. Pages are emitted as soon as they are complete, so a portrait image waiting
+// for its pair may end up after landscape images following it.
+func (g *Generator) AddAlbum(imgs []image.Image, opts ImageOptions) error {
+	var pending image.Image
+	for _, img := range imgs {
+		if isNilImage(img) {
+			return ErrNilImage
+		}
+		if b := img.Bounds(); b.Dx() >= b.Dy() {
+			if err := g.AddImage(img, opts); err != nil {
+				return err
+			}
+			continue
+		}
+		if pending == nil {
+			pending = img
+			continue
+		}
+		rect := g.pageRect()
+		half := Rect{X: rect.X, Y: rect.Y, W: rect.W / 2, H: rect.H}
+		other := half
+		other.X += half.W
+		if err := g.AddImagesAtRects([]image.Image{pending, img}, []Rect{half, other}, BackToFront, opts); err != nil {
+			return err
+		}
+		pending = nil
+	}
+	if pending != nil {
+		return g.AddImage(pending, opts)
+	}
+	return nil
+}
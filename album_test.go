@@ -0,0 +1,29 @@
+package p4p_test
+
+import (
+	"image"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddAlbum(t *testing.T) {
+	portrait := image.NewRGBA(image.Rect(0, 0, 300, 400))
+	landscape := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddAlbum([]image.Image{portrait, landscape, portrait}, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 pages, got:", len(pages))
+	}
+	// The landscape image is complete first, the portrait pair once the second portrait arrives.
+	full, paired := imageDraws(pages[0]), imageDraws(pages[1])
+	if len(full) != 1 || full[0].W < p4p.A4().W-0.01 {
+		t.Fatal("expected a full page landscape image, got:", full)
+	}
+	if len(paired) != 2 || paired[0].X+paired[0].W > paired[1].X+0.01 {
+		t.Fatal("expected two portraits side by side, got:", paired)
+	}
+}
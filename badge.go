@@ -0,0 +1,55 @@
+package p4p
+
+import (
+	"image/color"
+)
+
+// Appearance of the badge marking low resolution images; see SetLowResBadge.
+type BadgeOptions struct {
+	// Text of the badge (default: "LOW RES").
+	Text string
+	// Fill color of the badge, printed white on it (default: red).
+	Color color.Color
+	// Font size in points (default: 10).
+	Size float64
+}
+
+// For proofing, draws a badge in the top right corner of every image added afterwards that is
+// placed at less than dpi dots per inch on either axis. A dpi of 0 or less disables the badge.
+func (g *Generator) SetLowResBadge(dpi float64, opts BadgeOptions) {
+	if opts.Text == "" {
+		opts.Text = "LOW RES"
+	}
+	if opts.Color == nil {
+		opts.Color = color.RGBA{R: 0xff, A: 0xff}
+	}
+	if opts.Size <= 0 {
+		opts.Size = 10
+	}
+	g.lowResDPI = dpi
+	g.lowResBadge = opts
+}
+
+// Draws the low resolution badge in the top right corner of rect (in points) if an image of
+// wPx by hPx pixels placed at size w by h falls short of the threshold.
+func (g *Generator) drawLowResBadge(rect Rect, wPx, hPx int, w, h float64) {
+	if g.lowResDPI <= 0 || float64(wPx)*72/w >= g.lowResDPI && float64(hPx)*72/h >= g.lowResDPI {
+		return
+	}
+	if g.tr == nil {
+		// Core fonts only support cp1252.
+		g.tr = g.pdf.UnicodeTranslatorFromDescriptor("")
+	}
+	b := g.lowResBadge
+	text := g.tr(b.Text)
+	g.pdf.SetFont("Helvetica", "B", b.Size)
+	pad := b.Size / 2
+	bw, bh := g.pdf.GetStringWidth(text)+2*pad, b.Size+pad
+	x, y := rect.X+rect.W-bw-pad, rect.Y+pad
+	setFillColor(g.pdf, g.color(b.Color))
+	g.pdf.Rect(x, y, bw, bh, "F")
+	g.pdf.SetTextColor(0xff, 0xff, 0xff)
+	g.pdf.SetXY(x, y)
+	g.pdf.CellFormat(bw, bh, text, "", 0, "CM", false, 0, "")
+	g.pdf.SetTextColor(0, 0, 0)
+}
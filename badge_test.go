@@ -0,0 +1,28 @@
+package p4p_test
+
+import (
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestLowResBadge(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetLowResBadge(150, p4p.BadgeOptions{})
+	// At its pixel size, scaled down to 288 DPI, scaled up to fit the page.
+	for _, opts := range []p4p.ImageOptions{{}, {Scale: 0.25}, {Mode: p4p.Fit}} {
+		if err := g.AddImageFile("gophers/gopher.png", opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.SetLowResBadge(0, p4p.BadgeOptions{})
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for i, page := range pageContents(t, writePDF(t, g)) {
+		if badge := strings.Contains(page, "(LOW RES)Tj"); badge != (i == 0 || i == 2) {
+			t.Fatal("wrong badge on page", i+1, "got badge:", badge)
+		}
+	}
+}
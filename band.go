@@ -0,0 +1,93 @@
+package p4p
+
+import (
+	"image"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// An image repeated across the full width of every page.
+type band struct {
+	name     string
+	typ      string
+	wPx, hPx int
+	// Height reserved for the band, in points.
+	height float64
+}
+
+// Sets an image drawn at the top of every page added afterwards, fit into a band of the given height (in points).
+// Images are laid out in the remaining area below the band. Passing a nil image removes the header.
+func (g *Generator) SetHeaderImage(img image.Image, height float64) error {
+	return g.setBand(&g.header, g.imageNamePrefix+"header", img, height)
+}
+
+// Sets an image drawn at the bottom of every page added afterwards, fit into a band of the given height (in points).
+// Images are laid out in the remaining area above the band. Passing a nil image removes the footer.
+func (g *Generator) SetFooterImage(img image.Image, height float64) error {
+	return g.setBand(&g.footer, g.imageNamePrefix+"footer", img, height)
+}
+
+func (g *Generator) setBand(b **band, name string, img image.Image, height float64) error {
+	if isNilImage(img) {
+		*b = nil
+		return nil
+	}
+	if g.monochrome {
+		img = grayscale(img)
+	}
+	typ, data, err := encodeImage(img, ImageOptions{})
+	if err != nil {
+		return err
+	}
+	// gofpdf keeps the first image registered under a name, so every band gets a name of its own.
+	name += "_" + strconv.Itoa(g.imageIndex)
+	g.imageIndex++
+	info := g.pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: gofpdfType(typ)}, data)
+	if err := g.pdf.Error(); err != nil {
+		// Nothing has been drawn, the document is fine without the band.
+		g.pdf.ClearError()
+		return err
+	}
+	if cmyk, ok := img.(*image.CMYK); ok {
+		if err := g.setCMYK(info, cmyk); err != nil {
+			return err
+		}
+	}
+	*b = &band{
+		name:   name,
+		typ:    gofpdfType(typ),
+		wPx:    int(info.Width()),
+		hPx:    int(info.Height()),
+		height: height,
+	}
+	return nil
+}
+
+// Draws the header and footer images on the current page.
+func (g *Generator) drawBands() {
+	for _, b := range []*band{g.header, g.footer} {
+		if b == nil {
+			continue
+		}
+		rect := Rect{W: g.pageSize.W, H: b.height}
+		if b == g.footer {
+			rect.Y = g.pageSize.H - b.height
+		}
+		l := RenderLayoutInRect(rect, Point, b.wPx, b.hPx, ImageOptions{Mode: Fit})
+		g.pdf.ImageOptions(b.name, l.X, l.Y, l.W, l.H, false, gofpdf.ImageOptions{ImageType: b.typ}, 0, "")
+	}
+}
+
+// Returns the area of a page not taken up by header and footer images, in points.
+func (g *Generator) pageRect() Rect {
+	rect := Rect{W: g.pageSize.W, H: g.pageSize.H}
+	if g.header != nil {
+		rect.Y += g.header.height
+		rect.H -= g.header.height
+	}
+	if g.footer != nil {
+		rect.H -= g.footer.height
+	}
+	return rect
+}
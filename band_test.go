@@ -0,0 +1,87 @@
+package p4p_test
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestHeaderFooterImages(t *testing.T) {
+	const height = 50
+	pg := p4p.A4()
+	g := p4p.NewGenerator(pg)
+	logo := decodeFile(t, "gophers/gopher2.png")
+	if err := g.SetHeaderImage(logo, height); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetFooterImage(logo, height); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"gophers/gopher.png", "gophers/gopher1.jpg"} {
+		if err := g.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 pages, got:", len(pages))
+	}
+	const eps = 0.01
+	for i, page := range pages {
+		draws := imageDraws(page)
+		if len(draws) != 3 {
+			t.Fatal("expected header, footer and image on page", i+1, "got:", draws)
+		}
+		// PDF coordinates start at the bottom of the page.
+		header, footer, img := draws[0], draws[1], draws[2]
+		if header.Y < pg.H-height-eps || header.Y+header.H > pg.H+eps {
+			t.Fatal("header not at the top of page", i+1, "got:", header)
+		}
+		if footer.Y < -eps || footer.Y+footer.H > height+eps {
+			t.Fatal("footer not at the bottom of page", i+1, "got:", footer)
+		}
+		if img.Y < height-eps || img.Y+img.H > pg.H-height+eps {
+			t.Fatal("image overlaps header or footer on page", i+1, "got:", img)
+		}
+	}
+}
+
+func TestReplaceHeaderImage(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	for _, w := range []int{100, 300} {
+		if err := g.SetHeaderImage(image.NewGray(image.Rect(0, 0, w, 10)), 50); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := writePDF(t, g)
+	for _, want := range []string{"/Width 100\n", "/Width 300\n"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Fatalf("expected a header image with %q", want)
+		}
+	}
+	pages := pageContents(t, pdf)
+	// Fitted to the band, the wider second header is less than half as high as the first.
+	if h1, h2 := imageDraws(pages[0])[0].H, imageDraws(pages[1])[0].H; h2 > h1/2 {
+		t.Fatal("expected the second page to show the replaced header, got heights:", h1, h2)
+	}
+}
+
+func TestBadHeaderImage(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	// Embedded as a 16-bit PNG, which gofpdf cannot read.
+	if err := g.SetHeaderImage(image.NewNRGBA64(image.Rect(0, 0, 10, 10)), 50); err == nil {
+		t.Fatal("expected an error setting a header image gofpdf cannot read")
+	}
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 1 || len(imageDraws(pages[0])) != 1 {
+		t.Fatal("expected the image without a header, got:", pages)
+	}
+}
@@ -0,0 +1,73 @@
+package p4p
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Sets the core font family, Helvetica, Times or Courier, and the size in points of all captions
+// (default: Helvetica and 10). An empty family or a size of zero or less keeps the default.
+func (g *Generator) SetCaptionFont(family string, size float64) {
+	g.captionFont, g.captionSize = family, size
+}
+
+// Captions images added with AddImageFile without a caption of their own with their file names,
+// without the extension.
+func (g *Generator) SetFilenameCaptions(enabled bool) {
+	g.filenameCaptions = enabled
+}
+
+// Returns the caption font family and size.
+func (g *Generator) captionFontSpec() (string, float64) {
+	family, size := g.captionFont, g.captionSize
+	if family == "" {
+		family = "Helvetica"
+	}
+	if size <= 0 {
+		size = 10
+	}
+	return family, size
+}
+
+// Returns the height reserved below a captioned image, in points.
+func (g *Generator) captionHeight() float64 {
+	_, size := g.captionFontSpec()
+	return 2 * size
+}
+
+// Returns the caption of an image read from path.
+func (g *Generator) fileCaption(path string, opts ImageOptions) string {
+	if opts.Caption != "" || !g.filenameCaptions {
+		return opts.Caption
+	}
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Sets a fmt format for all captions, receiving the running figure number and the image's caption,
+// e.g. "Figure %d: %s". Figures are numbered across the whole document, counting captioned images only.
+func (g *Generator) SetCaptionFormat(format string) {
+	g.captionFormat = format
+}
+
+// Returns the text of a caption, numbering it as the next figure if a caption format is set.
+func (g *Generator) captionText(caption string) string {
+	if caption == "" || g.captionFormat == "" {
+		return caption
+	}
+	g.figureCount++
+	return fmt.Sprintf(g.captionFormat, g.figureCount, caption)
+}
+
+// Draws a caption horizontally centered within rect (in points).
+func (g *Generator) drawCaption(text string, rect Rect) {
+	if g.tr == nil {
+		// Core fonts only support cp1252.
+		g.tr = g.pdf.UnicodeTranslatorFromDescriptor("")
+	}
+	family, size := g.captionFontSpec()
+	g.pdf.SetFont(family, "", size)
+	g.pdf.SetXY(rect.X, rect.Y)
+	g.pdf.CellFormat(rect.W, rect.H, g.tr(text), "", 0, "CM", false, 0, "")
+}
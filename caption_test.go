@@ -0,0 +1,133 @@
+package p4p_test
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestCaptionFormat(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetCaptionFormat("Figure %d: %s")
+	for _, caption := range []string{"First", "", "Second", "Third"} {
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Fit, Caption: caption}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := pageContents(t, writePDF(t, g))
+	want := []string{"(Figure 1: First)Tj", "", "(Figure 2: Second)Tj", "(Figure 3: Third)Tj"}
+	for i, page := range pages {
+		if want[i] == "" {
+			if strings.Contains(page, ")Tj") {
+				t.Fatal("uncaptioned image on page", i+1, "has a caption")
+			}
+		} else if !strings.Contains(page, want[i]) {
+			t.Fatal("page", i+1, "is missing caption", want[i])
+		}
+	}
+}
+
+func TestCaptionFormatSkipsFailedImages(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetCaptionFormat("Figure %d: %s")
+	g.SetMaxImageDimension(100, p4p.RejectOversized)
+	if err := g.AddImage(image.NewGray(image.Rect(0, 0, 200, 100)), p4p.ImageOptions{Caption: "Too large"}); err == nil {
+		t.Fatal("expected an error adding an oversized image")
+	}
+	if err := g.AddImage(image.NewGray(image.Rect(0, 0, 100, 100)), p4p.ImageOptions{Caption: "Added"}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 1 || !strings.Contains(pages[0], "(Figure 1: Added)Tj") {
+		t.Fatal("expected the added image to be figure 1, got:", pages)
+	}
+}
+
+func TestSlicesCaptionedOnce(t *testing.T) {
+	for name, opts := range map[string]p4p.ImageOptions{
+		"Columns":        {Columns: 2, Caption: "tall", AltText: "A tall image"},
+		"SplitTall":      {SplitTall: true, Caption: "tall", AltText: "A tall image"},
+		"FlowHorizontal": {FlowHorizontal: true, Caption: "wide", AltText: "A wide image"},
+	} {
+		img := image.NewGray(image.Rect(0, 0, 100, 2000))
+		if opts.FlowHorizontal {
+			img = image.NewGray(image.Rect(0, 0, 4000, 100))
+		}
+		g := p4p.NewGenerator(p4p.A4())
+		g.SetCaptionFormat("Figure %d: %s")
+		if err := g.AddImage(img, opts); err != nil {
+			t.Fatal(err)
+		}
+		pdf := writePDF(t, g)
+		if n := bytes.Count(pdf, []byte("/Alt (")); n != 1 {
+			t.Fatalf("%s: expected alt text once, got: %d", name, n)
+		}
+		pages := pageContents(t, pdf)
+		pg := p4p.A4()
+		colW := pg.W
+		if opts.Columns > 1 {
+			colW = (pg.W - 12) / 2
+		}
+		// Twice the default caption font size.
+		const captionH, eps = 20, 0.01
+		want := "(Figure 1: " + opts.Caption + ")Tj"
+		for i, page := range pages {
+			last := i == len(pages)-1
+			if strings.Contains(page, ")Tj") != last || last && !strings.Contains(page, want) {
+				t.Fatalf("%s: expected %s on the last page only, got on page %d: %s", name, want, i+1, page)
+			}
+			for _, s := range imageDraws(page) {
+				// Slices keep the scale of the ones without a caption.
+				if opts.FlowHorizontal && math.Abs(s.H-(pg.H-captionH)) > eps || !opts.FlowHorizontal && math.Abs(s.W-colW) > eps {
+					t.Fatalf("%s: slice on page %d is shrunk, got: %v", name, i+1, s)
+				}
+				// PDF coordinates start at the bottom of the page.
+				if last && s.Y < captionH-eps {
+					t.Fatalf("%s: slice on page %d overlaps the caption, got: %v", name, i+1, s)
+				}
+			}
+		}
+	}
+}
+
+func TestCaptionReservesSpace(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	// Tall image, so Fit is limited by the height left above the caption.
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Fit, Caption: "Gopher"}); err != nil {
+		t.Fatal(err)
+	}
+	img := imageDraws(pageContents(t, writePDF(t, g))[0])[0]
+	// PDF coordinates start at the bottom of the page.
+	if img.Y < 20 || img.Y+img.H > p4p.A4().H {
+		t.Fatal("image overlaps the caption, got:", img)
+	}
+}
+
+func TestCaptionFont(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetCaptionFont("Times", 16)
+	g.SetFilenameCaptions(true)
+	for _, caption := range []string{"", "Custom"} {
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Fit, Caption: caption}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := writePDF(t, g)
+	if !strings.Contains(string(pdf), "/BaseFont /Times-Roman") {
+		t.Fatal("expected captions in Times")
+	}
+	for i, page := range pageContents(t, pdf) {
+		want := []string{"(gopher)Tj", "(Custom)Tj"}[i]
+		if !strings.Contains(page, want) || !strings.Contains(page, " 16.00 Tf") {
+			t.Fatal("page", i+1, "is missing caption", want, "in 16pt, got:", page)
+		}
+		// The tall image is fitted to the height left above the caption, twice the font size.
+		if img := imageDraws(page)[0]; math.Abs(img.Y-32) > 0.01 {
+			t.Fatal("expected the image to end 32pt above the bottom of page", i+1, "got:", img)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package p4p
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Type encodeImage gives CMYK images, which are embedded losslessly as DeviceCMYK images. gofpdf
+// cannot read CMYK data, so they are encoded as PNG placeholders carrying the four channels in
+// their RGBA samples, and replaced in the output; see putCMYK.
+const cmykType = "cmyk"
+
+// Returns the image type gofpdf registers an image of type typ as.
+func gofpdfType(typ string) string {
+	if typ == cmykType {
+		return "png"
+	}
+	return typ
+}
+
+// Encodes the CMYK image as a placeholder PNG with the same samples.
+func encodeCMYK(img *image.CMYK) (*bytes.Buffer, error) {
+	b := new(bytes.Buffer)
+	// Both store one byte per channel, four per pixel.
+	err := png.Encode(b, &image.NRGBA{Pix: img.Pix, Stride: img.Stride, Rect: img.Rect})
+	return b, err
+}
+
+// Decodes a placeholder PNG written by encodeCMYK.
+func decodeCMYK(r io.Reader) (*image.CMYK, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	switch m := img.(type) {
+	case *image.NRGBA:
+		return &image.CMYK{Pix: m.Pix, Stride: m.Stride, Rect: m.Rect}, nil
+	case *image.RGBA:
+		// Written without an alpha channel, as all samples of the black channel were 0xff.
+		return &image.CMYK{Pix: m.Pix, Stride: m.Stride, Rect: m.Rect}, nil
+	}
+	return nil, errors.New("p4p: malformed CMYK placeholder")
+}
+
+// A CMYK image to replace the placeholder gofpdf embedded.
+type cmykImage struct {
+	w, h int
+	// The samples, Flate compressed.
+	data []byte
+}
+
+// Returns the CMYK image the pending image is embedded as, or nil if it is not a CMYK image.
+func (g *Generator) cmykPending(p *pendingImage) (*image.CMYK, error) {
+	if !p.changed && p.typ != cmykType {
+		return nil, nil
+	}
+	// Decoded before gofpdf reads the placeholder.
+	img, err := g.decodePending(p)
+	if err != nil {
+		return nil, err
+	}
+	cmyk, _ := img.(*image.CMYK)
+	return cmyk, nil
+}
+
+// Records the samples of a registered CMYK image. Identical images share one embedded object.
+func (g *Generator) setCMYK(info *gofpdf.ImageInfoType, img *image.CMYK) error {
+	id, err := imageID(info)
+	if err != nil {
+		return err
+	}
+	if _, ok := g.cmyk[id]; ok {
+		return nil
+	}
+	if g.cmyk == nil {
+		g.cmyk = make(map[string]cmykImage)
+	}
+	b := img.Bounds()
+	var samples bytes.Buffer
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := img.PixOffset(b.Min.X, y)
+		samples.Write(img.Pix[i : i+4*b.Dx()])
+	}
+	g.cmyk[id] = cmykImage{w: b.Dx(), h: b.Dy(), data: compress(samples.Bytes())}
+	return nil
+}
+
+// Replaces the placeholders of CMYK images with DeviceCMYK images, found through the resource dictionary.
+func (g *Generator) putCMYK(p *pdfFile) {
+	smaskRe := regexp.MustCompile(`/SMask (\d+) 0 R`)
+	for id, img := range g.cmyk {
+		re := regexp.MustCompile(`/I` + id + ` (\d+) 0 R`)
+		for _, obj := range p.objs {
+			m := re.FindSubmatch(obj)
+			if m == nil {
+				continue
+			}
+			n, _ := strconv.Atoi(string(m[1]))
+			if s := smaskRe.FindSubmatch(p.objs[n]); s != nil {
+				// The placeholder's alpha channel, which nothing refers to anymore.
+				sn, _ := strconv.Atoi(string(s[1]))
+				p.set(sn, "null")
+			}
+			p.set(n, fmt.Sprintf("<</Type /XObject\n/Subtype /Image\n/Width %d\n/Height %d\n/ColorSpace /DeviceCMYK\n/BitsPerComponent 8\n/Filter /FlateDecode\n/Length %d>>\nstream\n%s\nendstream",
+				img.w, img.h, len(img.data), img.data))
+			break
+		}
+	}
+}
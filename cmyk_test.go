@@ -0,0 +1,62 @@
+package p4p_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestCMYK(t *testing.T) {
+	img := image.NewCMYK(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			// Black in every other column, so the placeholder has an alpha channel, which gofpdf embeds as a soft mask.
+			img.SetCMYK(x, y, color.CMYK{C: uint8(x * 6), M: uint8(y * 8), Y: uint8(x + y), K: uint8(x % 2 * 0xff)})
+		}
+	}
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.SetHeaderImage(img, 72); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImage(img, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+
+	names := regexp.MustCompile(`/(I\w+) Do`).FindAllStringSubmatch(pageContents(t, pdf)[0], -1)
+	if len(names) != 2 {
+		t.Fatal("expected the header and the image, got:", len(names))
+	}
+	for i, name := range names {
+		ref := regexp.MustCompile(`/` + name[1] + ` (\d+) 0 R`).FindSubmatch(pdf)
+		obj := regexp.MustCompile(`(?s)\n` + string(ref[1]) + ` 0 obj\n(.*?)\nstream\n(.*?)\nendstream`).FindSubmatch(pdf)
+		if obj == nil {
+			t.Fatal("missing image object", i+1)
+		}
+		for _, s := range []string{"/ColorSpace /DeviceCMYK", "/BitsPerComponent 8", "/Filter /FlateDecode", "/Width 40", "/Height 30"} {
+			if !bytes.Contains(obj[1], []byte(s)) {
+				t.Fatalf("image %d is missing %s, got: %s", i+1, s, obj[1])
+			}
+		}
+		if bytes.Contains(obj[1], []byte("/SMask")) || bytes.Contains(obj[1], []byte("/DecodeParms")) {
+			t.Fatalf("image %d keeps entries of its placeholder, got: %s", i+1, obj[1])
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(obj[2]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(samples, img.Pix) {
+			t.Fatal("image", i+1, "does not keep the CMYK samples")
+		}
+	}
+}
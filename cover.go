@@ -0,0 +1,23 @@
+package p4p
+
+// Lays out the first image added to the document, which becomes page one, with opts instead of the
+// options it is added with, e.g. as a full-bleed Fill cover of a photo book whose other pages Fit.
+// Only applies to images that get a page of their own. Passing nil removes the cover options.
+func (g *Generator) SetCoverOptions(opts *ImageOptions) {
+	if opts == nil {
+		g.cover = nil
+		return
+	}
+	o := *opts
+	g.cover = &o
+}
+
+// Returns the options of an image about to get a page of its own.
+func (g *Generator) pageOptions(opts ImageOptions) ImageOptions {
+	if g.cover == nil || g.pdf.PageNo() > 0 {
+		return opts
+	}
+	cover := *g.cover
+	cover.source, cover.decoded = opts.source, opts.decoded
+	return cover
+}
@@ -0,0 +1,53 @@
+package p4p_test
+
+import (
+	"math"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestCoverOptions(t *testing.T) {
+	pg := p4p.A4()
+	g := p4p.NewGenerator(pg)
+	g.SetCoverOptions(&p4p.ImageOptions{Mode: p4p.Fill})
+	// gopher1.jpg is almost square.
+	paths := []string{"gophers/gopher1.jpg", "gophers/gopher1.jpg", "gophers/gopher1.jpg"}
+	if err := g.AddImageFiles(paths, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 3 {
+		t.Fatal("expected 3 pages, got:", len(pages))
+	}
+	const eps = 0.01
+	// Filled to the page height, cropped at the sides.
+	if d := imageDraws(pages[0])[0]; math.Abs(d.H-pg.H) > eps || d.W < pg.W-eps {
+		t.Fatal("expected a filled cover, got:", d)
+	}
+	for i, page := range pages[1:] {
+		if d := imageDraws(page)[0]; math.Abs(d.W-pg.W) > eps || d.H >= pg.H {
+			t.Fatal("expected a fit image on page", i+2, "got:", d)
+		}
+	}
+}
+
+func TestCoverOptionsInMemory(t *testing.T) {
+	// Images from memory added with Fill are only encoded once placed, which must also happen
+	// when the cover options do not crop them.
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetCoverOptions(&p4p.ImageOptions{Mode: p4p.Fit})
+	img := decodeFile(t, "gophers/gopher1.jpg")
+	for i := 0; i < 2; i++ {
+		if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fill}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if d := imageDraws(pages[0])[0]; d.H >= p4p.A4().H {
+		t.Fatal("expected a fit cover, got:", d)
+	}
+	if d := imageDraws(pages[1])[0]; math.Abs(d.H-p4p.A4().H) > 0.01 {
+		t.Fatal("expected a filled image on page 2, got:", d)
+	}
+}
@@ -0,0 +1,51 @@
+package p4p
+
+import (
+	"image"
+)
+
+// Reports whether images are cropped to their visible part before embedding them, rather than
+// embedding them whole and clipping them. Rotated images may show parts outside the clip's
+// unrotated extent, so they are embedded whole.
+func (opts ImageOptions) cropsPixels() bool {
+	return opts.Mode == Fill && opts.Rotate == 0 && opts.layoutSize == (image.Point{})
+}
+
+// Crops the image to the part visible when laid out within area, and returns options laying out the
+// cropped image as part of the whole one. Images that need no cropping are left unchanged.
+func (g *Generator) cropToLayout(p *pendingImage, area Rect, opts ImageOptions) (ImageOptions, error) {
+	size, ok, err := p.size()
+	if err != nil || !ok {
+		return opts, err
+	}
+	l := RenderLayoutInRect(area, Point, size.X, size.Y, opts)
+	// The far edges are rounded down, keep the partially visible pixels; the clip hides the excess.
+	visible := image.Rect(l.Crop.Min.X, l.Crop.Min.Y, min(l.Crop.Max.X+1, size.X), min(l.Crop.Max.Y+1, size.Y))
+	if !l.NeedsCrop || visible.Empty() || visible == (image.Rectangle{Max: size}) {
+		return opts, nil
+	}
+
+	img, err := g.decodePending(p)
+	if err != nil {
+		return opts, err
+	}
+	p.replace(subImage(img, visible.Add(img.Bounds().Min)))
+	opts.layoutSize = size
+	opts.pixelCrop = visible
+	return opts, nil
+}
+
+// Returns where the registered image goes when the whole image is laid out at x, y, w, h.
+func (opts ImageOptions) drawnRect(x, y, w, h float64) Rect {
+	c := opts.pixelCrop
+	if c.Empty() {
+		return Rect{X: x, Y: y, W: w, H: h}
+	}
+	pxW, pxH := w/float64(opts.layoutSize.X), h/float64(opts.layoutSize.Y)
+	return Rect{
+		X: x + float64(c.Min.X)*pxW,
+		Y: y + float64(c.Min.Y)*pxH,
+		W: float64(c.Dx()) * pxW,
+		H: float64(c.Dy()) * pxH,
+	}
+}
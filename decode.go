@@ -0,0 +1,87 @@
+package p4p
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	// Registers WebP with image.Decode.
+	_ "golang.org/x/image/webp"
+)
+
+// Returned when decoding an image takes longer than the timeout set with SetDecodeTimeout.
+var ErrDecodeTimeout = errors.New("p4p: decoding image timed out")
+
+// Limits how long decoding a single image may take, protecting a batch against malicious or huge
+// images. A decoder that timed out keeps running in the background until its reader returns.
+// Zero, the default, disables the timeout.
+func (g *Generator) SetDecodeTimeout(d time.Duration) {
+	g.decodeTimeout = d
+}
+
+// Decodes an image with any registered decoder, giving up after the decode timeout.
+func (g *Generator) decodeImage(r io.Reader) (image.Image, error) {
+	if g.decodeTimeout <= 0 {
+		img, _, err := image.Decode(r)
+		return img, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), g.decodeTimeout)
+	defer cancel()
+	type result struct {
+		img image.Image
+		err error
+	}
+	// Buffered, so that a decoder finishing after the timeout doesn't block forever.
+	done := make(chan result, 1)
+	go func() {
+		img, _, err := image.Decode(r)
+		done <- result{img, err}
+	}()
+	select {
+	case res := <-done:
+		return res.img, res.err
+	case <-ctx.Done():
+		return nil, ErrDecodeTimeout
+	}
+}
+
+// Decodes an image in any registered format from r and adds it as a new page, turned upright according
+// to its EXIF orientation.
+func (g *Generator) AddImageReader(r io.Reader, opts ImageOptions) error {
+	// The EXIF orientation precedes the image data, it has been read by the time the image is decoded.
+	var data bytes.Buffer
+	img, err := g.decodeImage(io.TeeReader(r, &data))
+	if err != nil {
+		return err
+	}
+	// Decoded before taking the generator's turn, so that concurrent calls decode in parallel.
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.addDecodedImage(orient(img, exifOrientation(data.Bytes())), opts)
+}
+
+// Adds an encoded image as a new page. JPEG, PNG and GIF data is embedded as it is, without decoding
+// and re-encoding it; other formats with a registered decoder, and images that need turning upright
+// according to their EXIF orientation, are decoded first.
+func (g *Generator) AddImageBytes(data []byte, opts ImageOptions) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.checkColorProfile(opts.source, data)
+	defer g.recordGeoLocation(g.pdf.PageNo(), data)
+	switch mediaType := http.DetectContentType(data); mediaType {
+	case "image/jpeg", "image/png", "image/gif":
+		if !opts.needsDecoding() && exifOrientation(data) == 1 {
+			return g.addImage(strings.TrimPrefix(mediaType, "image/"), bytes.NewReader(data), opts)
+		}
+	}
+	img, err := g.decodeOriented(data)
+	if err != nil {
+		return err
+	}
+	return g.addDecodedImage(img, opts)
+}
@@ -0,0 +1,108 @@
+package p4p_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+	"golang.org/x/image/webp"
+)
+
+// Blocks every read until released.
+type slowReader struct {
+	r       io.Reader
+	release chan struct{}
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	<-s.release
+	return s.r.Read(p)
+}
+
+func TestDecodeTimeout(t *testing.T) {
+	data, err := os.ReadFile("gophers/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetDecodeTimeout(50 * time.Millisecond)
+
+	slow := &slowReader{r: bytes.NewReader(data), release: make(chan struct{})}
+	defer close(slow.release)
+	start := time.Now()
+	if err := g.AddImageReader(slow, p4p.ImageOptions{}); !errors.Is(err, p4p.ErrDecodeTimeout) {
+		t.Fatal("expected ErrDecodeTimeout, got:", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatal("timeout fired late, after:", elapsed)
+	}
+	if err := g.AddImageReader(bytes.NewReader(data), p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if pages := pageContents(t, writePDF(t, g)); len(pages) != 1 {
+		t.Fatal("expected only the decoded image's page, got:", len(pages))
+	}
+}
+
+func TestAddImageFileWebP(t *testing.T) {
+	f, err := os.Open("gophers/gopher3.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, err := webp.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImageFile("gophers/gopher3.webp", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	pages := pageContents(t, pdf)
+	if len(pages) != 1 {
+		t.Fatal("expected 1 page, got:", len(pages))
+	}
+	draws := imageDraws(pages[0])
+	if len(draws) != 1 || draws[0].W != float64(cfg.Width) || draws[0].H != float64(cfg.Height) {
+		t.Fatalf("expected one %dx%d image, got: %v", cfg.Width, cfg.Height, draws)
+	}
+	// The WebP has an alpha channel, so it is embedded as a PNG.
+	if !bytes.Contains(pdf, []byte("/SMask")) {
+		t.Fatal("expected the image to keep its alpha channel")
+	}
+}
+
+func TestAddImageBytes(t *testing.T) {
+	jpg, err := os.ReadFile("gophers/gopher1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImageBytes(jpg, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	webpData, err := os.ReadFile("gophers/gopher3.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageBytes(webpData, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageBytes([]byte("not an image"), p4p.ImageOptions{}); err == nil {
+		t.Fatal("expected error for data that is not an image")
+	}
+	pdf := writePDF(t, g)
+	if n := len(pageContents(t, pdf)); n != 2 {
+		t.Fatal("expected 2 pages, got:", n)
+	}
+	// The JPEG is embedded as it is.
+	if !bytes.Contains(pdf, jpg) {
+		t.Fatal("expected the original JPEG data in the output")
+	}
+}
@@ -0,0 +1,179 @@
+package p4p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"io"
+	"math"
+)
+
+// Returns the JPEG data with its JFIF density set to the resolution the image is placed at within
+// area (in points), so that tools extracting the image from the PDF see its effective DPI.
+func withPlacementDPI(r io.Reader, area Rect, opts ImageOptions) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		// Left for gofpdf to report.
+		return bytes.NewReader(data), nil
+	}
+	layoutW, layoutH := cfg.Width, cfg.Height
+	if opts.layoutSize != (image.Point{}) {
+		layoutW, layoutH = opts.layoutSize.X, opts.layoutSize.Y
+	}
+	l := RenderLayoutInRect(area, Point, layoutW, layoutH, opts)
+	d := opts.drawnRect(l.X, l.Y, l.W, l.H)
+	return bytes.NewReader(setJPEGDensity(data, placementDPI(cfg.Width, d.W), placementDPI(cfg.Height, d.H))), nil
+}
+
+// Returns the resolution of px pixels spanning pt points.
+func placementDPI(px int, pt float64) uint16 {
+	if pt <= 0 {
+		return 1
+	}
+	return uint16(min(max(math.Round(float64(px)*72/pt), 1), math.MaxUint16))
+}
+
+// Returns the JPEG data with the density of its JFIF segment set in dots per inch, adding the
+// segment if there is none.
+func setJPEGDensity(data []byte, xdpi, ydpi uint16) []byte {
+	const jfifMarker = "JFIF\x00"
+	if len(data) < 2 {
+		return data
+	}
+	for seg := data[2:]; len(seg) >= 4 && seg[0] == 0xff; {
+		marker := seg[1]
+		n := int(binary.BigEndian.Uint16(seg[2:]))
+		if marker == 0xda || marker == 0xd9 || n < 2 || len(seg) < 2+n {
+			break
+		}
+		if marker == 0xe0 && n >= 16 && string(seg[4:9]) == jfifMarker {
+			out := append([]byte{}, data...)
+			density := out[len(data)-len(seg)+11:]
+			// Units, then horizontal and vertical density.
+			density[0] = 1
+			binary.BigEndian.PutUint16(density[1:], xdpi)
+			binary.BigEndian.PutUint16(density[3:], ydpi)
+			return out
+		}
+		seg = seg[2+n:]
+	}
+	app0 := []byte{0xff, 0xe0, 0, 16}
+	app0 = append(app0, jfifMarker...)
+	// Version 1.01, dots per inch, no thumbnail.
+	app0 = append(app0, 1, 1, 1)
+	app0 = binary.BigEndian.AppendUint16(app0, xdpi)
+	app0 = binary.BigEndian.AppendUint16(app0, ydpi)
+	app0 = append(app0, 0, 0)
+	return append(append(append([]byte{}, data[:2]...), app0...), data[2:]...)
+}
+
+// Returns the physical size of a JPEG or PNG image in points according to the resolution in its
+// metadata, if it has any.
+func dpiPageSize(data []byte) (PageSize, bool) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return PageSize{}, false
+	}
+	xdpi, ydpi, ok := imageDPI(data)
+	if !ok {
+		return PageSize{}, false
+	}
+	return PageSize{W: float64(cfg.Width) / xdpi * 72, H: float64(cfg.Height) / ydpi * 72, Unit: Point}, true
+}
+
+// Returns the horizontal and vertical resolution stored in a JPEG's JFIF segment or a PNG's pHYs chunk,
+// in dots per inch.
+func imageDPI(data []byte) (xdpi, ydpi float64, ok bool) {
+	const pngSignature = "\x89PNG\r\n\x1a\n"
+	if len(data) > len(pngSignature) && string(data[:len(pngSignature)]) == pngSignature {
+		for chunk := data[len(pngSignature):]; len(chunk) >= 12; {
+			n := int(binary.BigEndian.Uint32(chunk))
+			typ := string(chunk[4:8])
+			if typ == "IDAT" || len(chunk) < 12+n {
+				break
+			}
+			// Pixels per unit on both axes, then the unit, where 1 is the meter.
+			if typ == "pHYs" && n == 9 && chunk[16] == 1 {
+				x, y := binary.BigEndian.Uint32(chunk[8:]), binary.BigEndian.Uint32(chunk[12:])
+				if x > 0 && y > 0 {
+					return float64(x) * 0.0254, float64(y) * 0.0254, true
+				}
+			}
+			chunk = chunk[12+n:]
+		}
+		return 0, 0, false
+	}
+
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return 0, 0, false
+	}
+	for seg := data[2:]; len(seg) >= 4 && seg[0] == 0xff; {
+		marker := seg[1]
+		n := int(binary.BigEndian.Uint16(seg[2:]))
+		if marker == 0xda || marker == 0xd9 || n < 2 || len(seg) < 2+n {
+			break
+		}
+		if marker == 0xe0 && n >= 16 && string(seg[4:9]) == "JFIF\x00" {
+			x, y := float64(binary.BigEndian.Uint16(seg[12:])), float64(binary.BigEndian.Uint16(seg[14:]))
+			if x == 0 || y == 0 {
+				break
+			}
+			// Units: 0 for just the aspect ratio, 1 for dots per inch and 2 for dots per centimeter.
+			switch seg[11] {
+			case 1:
+				return x, y, true
+			case 2:
+				return x * 2.54, y * 2.54, true
+			}
+			break
+		}
+		seg = seg[2+n:]
+	}
+	return 0, 0, false
+}
+
+// How page sizes computed from images' resolution are rounded; see SetPageSizeSnapping.
+type PageSizeSnapping struct {
+	// Snap sizes to a standard page size (in either orientation) if both sides are within Tolerance of it.
+	Standard bool
+	// Largest difference between a size and a standard page size snapped to, in points (default: 6, about 2mm).
+	Tolerance float64
+	// Round sizes not snapped to a standard size to a multiple of Increment (in Unit), e.g. 1 with Millimeter.
+	Increment float64
+	Unit      Unit
+}
+
+// Rounds page sizes computed by SetPageSizeFromDPI, avoiding fractional sizes such as 8.503x11.002in.
+func (g *Generator) SetPageSizeSnapping(s PageSizeSnapping) {
+	if s.Tolerance <= 0 {
+		s.Tolerance = 6
+	}
+	if s.Unit == 0 {
+		s.Unit = Point
+	}
+	g.pageSizeSnapping = s
+}
+
+// Returns the page size (in points) rounded according to s.
+func (s PageSizeSnapping) snap(size PageSize) PageSize {
+	if s.Standard {
+		for _, std := range StandardPageSizes() {
+			for _, std := range []PageSize{std, std.Rotate()} {
+				if math.Abs(size.W-std.W) <= s.Tolerance && math.Abs(size.H-std.H) <= s.Tolerance {
+					return std
+				}
+			}
+		}
+	}
+	if s.Increment > 0 {
+		inc := s.Increment * float64(s.Unit)
+		size.W = max(math.Round(size.W/inc), 1) * inc
+		size.H = max(math.Round(size.H/inc), 1) * inc
+	}
+	return size
+}
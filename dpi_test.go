@@ -0,0 +1,189 @@
+package p4p_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Returns the JFIF density of each JPEG image embedded in the PDF in dots per inch, by XObject name.
+func jpegDensities(t *testing.T, pdf []byte) map[string][2]int {
+	t.Helper()
+	dpis := make(map[string][2]int)
+	for _, m := range regexp.MustCompile(`/(I[0-9a-f]{40}) (\d+) 0 R`).FindAllSubmatch(pdf, -1) {
+		obj := bytes.Index(pdf, []byte("\n"+string(m[2])+" 0 obj\n"))
+		if obj < 0 {
+			t.Fatal("missing image object", string(m[2]))
+		}
+		rest := pdf[obj:]
+		dict := rest[:bytes.Index(rest, []byte("stream\n"))]
+		if !bytes.Contains(dict, []byte("/Filter /DCTDecode")) {
+			continue
+		}
+		start := len(dict) + len("stream\n")
+		data := rest[start : start+bytes.Index(rest[start:], []byte("\nendstream"))]
+		j := bytes.Index(data, []byte("JFIF\x00"))
+		if j < 0 || j+12 > len(data) || data[j+7] != 1 {
+			t.Fatal("embedded JPEG without density in dots per inch")
+		}
+		dpis[string(m[1])] = [2]int{int(binary.BigEndian.Uint16(data[j+8:])), int(binary.BigEndian.Uint16(data[j+10:]))}
+	}
+	return dpis
+}
+
+func TestPlacementDPI(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	// gopher1.jpg is 316x317 pixels.
+	for _, mode := range []p4p.Mode{p4p.Center, p4p.Fit} {
+		if err := g.AddImageFile("gophers/gopher1.jpg", p4p.ImageOptions{Mode: mode}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := writePDF(t, g)
+	dpis := jpegDensities(t, pdf)
+	sizes := [][2]int{{300, 200}, {316, 317}, {316, 317}}
+	if len(dpis) != len(sizes) {
+		t.Fatal("expected", len(sizes), "embedded JPEG images, got:", len(dpis))
+	}
+	var placed [][2]int
+	for i, page := range pageContents(t, pdf) {
+		d := imageDraws(page)[0]
+		dpi := dpis[regexp.MustCompile(`/(I\w+) Do`).FindStringSubmatch(page)[1]]
+		want := [2]int{int(math.Round(float64(sizes[i][0]) * 72 / d.W)), int(math.Round(float64(sizes[i][1]) * 72 / d.H))}
+		if dpi != want {
+			t.Fatal("wrong DPI of image", i+1, "got:", dpi, "want:", want)
+		}
+		placed = append(placed, dpi)
+	}
+	if placed[1] != [2]int{72, 72} {
+		t.Fatal("expected 72 DPI for an image placed at its pixel size, got:", placed[1])
+	}
+}
+
+// Returns the image encoded as PNG with a pHYs chunk of the given pixels per meter.
+func pngWithDensity(t *testing.T, img image.Image, ppm uint32) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	phys := binary.BigEndian.AppendUint32(nil, 9)
+	phys = append(phys, "pHYs"...)
+	phys = binary.BigEndian.AppendUint32(phys, ppm)
+	phys = binary.BigEndian.AppendUint32(phys, ppm)
+	phys = append(phys, 1)
+	phys = binary.BigEndian.AppendUint32(phys, crc32.ChecksumIEEE(phys[4:]))
+	// After the signature and the IHDR chunk.
+	return append(append(append([]byte{}, b.Bytes()[:33]...), phys...), b.Bytes()[33:]...)
+}
+
+func TestPageSizeFromDPI(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 255, 330))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	// JFIF segment at 30 DPI.
+	app0 := []byte{0xff, 0xe0, 0, 16, 'J', 'F', 'I', 'F', 0, 1, 1, 1, 0, 30, 0, 30, 0, 0}
+	jpgData := append(append([]byte{0xff, 0xd8}, app0...), jpg.Bytes()[2:]...)
+
+	// 1 pixel per 0.01 inch, or 3937 pixels per meter.
+	pngData := pngWithDensity(t, image.NewNRGBA(image.Rect(0, 0, 1100, 850)), 3937)
+
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "scan.jpg"), filepath.Join(dir, "scan.png")}
+	for i, data := range [][]byte{jpgData, pngData} {
+		if err := os.WriteFile(paths[i], data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetPageSizeFromDPI(true)
+	for _, path := range paths {
+		if err := g.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Without resolution metadata.
+	if err := g.AddImage(img, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	// Pages that differ from the default size have their own MediaBox.
+	boxes := regexp.MustCompile(`/Type /Page\n(?:/Parent 1 0 R\n)?(/MediaBox \[0 0 [\d.]+ [\d.]+\])?`).FindAllSubmatch(pdf, -1)
+	want := []string{"/MediaBox [0 0 612.00 792.00]", "/MediaBox [0 0 792.00 612.00]", ""}
+	if len(boxes) != len(want) {
+		t.Fatal("expected 3 pages, got:", len(boxes))
+	}
+	for i, box := range boxes {
+		if string(box[1]) != want[i] {
+			t.Errorf("page %d: expected %q, got %q", i+1, want[i], box[1])
+		}
+	}
+	// Laid out within the page's own size.
+	if d := imageDraws(pageContents(t, pdf)[0])[0]; math.Abs(d.W-612) > 0.01 || math.Abs(d.H-792) > 0.01 {
+		t.Error("expected the scan to fill its page, got:", d)
+	}
+}
+
+func TestPageSizeSnapping(t *testing.T) {
+	// An A4 scan at 300 DPI, or 11811 pixels per meter, is 595.20x841.92pt.
+	a4 := pngWithDensity(t, image.NewGray(image.Rect(0, 0, 2480, 3508)), 11811)
+	// 300x200 pixels at 100 DPI, or 3937 pixels per meter, is 216x144pt.
+	photo := pngWithDensity(t, image.NewGray(image.Rect(0, 0, 300, 200)), 3937)
+	dir := t.TempDir()
+	a4Path, photoPath := filepath.Join(dir, "a4.png"), filepath.Join(dir, "photo.png")
+	for path, data := range map[string][]byte{a4Path: a4, photoPath: photo} {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		snap  p4p.PageSizeSnapping
+		boxes []string
+	}{
+		{p4p.PageSizeSnapping{}, []string{"595.20 841.92", "216.00 144.00"}},
+		// A4 is the generator's page size, which needs no MediaBox of its own.
+		{p4p.PageSizeSnapping{Standard: true}, []string{"", "216.00 144.00"}},
+		{p4p.PageSizeSnapping{Increment: 1, Unit: p4p.Centimeter}, []string{"595.28 850.39", "226.77 141.73"}},
+		{p4p.PageSizeSnapping{Standard: true, Increment: 1, Unit: p4p.Centimeter}, []string{"", "226.77 141.73"}},
+	} {
+		g := p4p.NewGenerator(p4p.A4())
+		g.SetPageSizeFromDPI(true)
+		g.SetPageSizeSnapping(tc.snap)
+		for _, path := range []string{a4Path, photoPath} {
+			if err := g.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		boxes := regexp.MustCompile(`/Type /Page\n(?:/Parent 1 0 R\n)?(?:/MediaBox \[0 0 ([\d.]+ [\d.]+)\])?`).FindAllSubmatch(writePDF(t, g), -1)
+		if len(boxes) != 2 {
+			t.Fatal("expected 2 pages, got:", len(boxes))
+		}
+		for i, box := range boxes {
+			if string(box[1]) != tc.boxes[i] {
+				t.Errorf("%+v: page %d: expected %q, got %q", tc.snap, i+1, tc.boxes[i], box[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package p4p
+
+// Sets whether even pages are rotated by 180 degrees, so that they come out the right way up when
+// printed double-sided with tumble (short-edge) binding (default: false).
+func (g *Generator) SetTumbleDuplex(enabled bool) {
+	g.tumbleDuplex = enabled
+}
+
+func (g *Generator) putTumbleDuplex(p *pdfFile) {
+	if !g.tumbleDuplex {
+		return
+	}
+	for i, page := range p.pages() {
+		if i%2 == 1 {
+			p.extendDict(page, "/Rotate 180")
+		}
+	}
+}
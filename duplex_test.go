@@ -0,0 +1,23 @@
+package p4p_test
+
+import (
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestTumbleDuplex(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetTumbleDuplex(true)
+	for i := 0; i < 4; i++ {
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, page := range pageObjects(t, writePDF(t, g)) {
+		if rotated := strings.Contains(page.dict, "/Rotate 180"); rotated != (i%2 == 1) {
+			t.Fatal("wrong rotation of page", i+1, "got:", page.dict)
+		}
+	}
+}
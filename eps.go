@@ -0,0 +1,57 @@
+package p4p
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Returned when a feature depends on Ghostscript, but no gs executable is in PATH.
+var ErrGhostscriptNotFound = errors.New("p4p: gs not found in PATH (required to read EPS files)")
+
+// Rasterizes the EPS file at path at the given resolution, cropped to its bounding box, and adds it
+// as an image page. Areas the EPS does not paint are transparent. With Center, the image keeps the
+// size of the EPS unless opts.DPI is set.
+// Depends on a Ghostscript (gs) executable in PATH.
+func (g *Generator) AddEPSFile(path string, dpi float64, opts ImageOptions) error {
+	if dpi <= 0 {
+		return errors.New("p4p: dpi must be positive")
+	}
+	gs, err := exec.LookPath("gs")
+	if err != nil {
+		return ErrGhostscriptNotFound
+	}
+
+	dir, err := os.MkdirTemp("", "p4p_eps_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "eps.png")
+	var stderr bytes.Buffer
+	cmd := exec.Command(gs,
+		"-q", "-dSAFER", "-dBATCH", "-dNOPAUSE",
+		"-dEPSCrop",
+		"-sDEVICE=pngalpha",
+		fmt.Sprintf("-r%g", dpi),
+		"-sOutputFile="+out,
+		path,
+	)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("p4p: gs: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	if opts.DPI == 0 {
+		opts.DPI = dpi
+	}
+	if opts.source == "" {
+		opts.source = path
+	}
+	return g.AddImageFile(out, opts)
+}
@@ -0,0 +1,44 @@
+package p4p_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+const testEPS = `%!PS-Adobe-3.0 EPSF-3.0
+%%BoundingBox: 0 0 72 36
+1 0 0 setrgbcolor
+0 0 72 36 rectfill
+showpage
+`
+
+func TestAddEPSFile(t *testing.T) {
+	if _, err := exec.LookPath("gs"); err != nil {
+		t.Skip("gs not installed")
+	}
+	path := filepath.Join(t.TempDir(), "logo.eps")
+	if err := os.WriteFile(path, []byte(testEPS), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddEPSFile(path, 144, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 1 || len(imageDraws(pages[0])) != 1 {
+		t.Fatal("expected one page with the EPS, got:", pages)
+	}
+}
+
+func TestAddEPSFileMissingGhostscript(t *testing.T) {
+	t.Setenv("PATH", "")
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddEPSFile("logo.eps", 300, p4p.ImageOptions{}); err != p4p.ErrGhostscriptNotFound {
+		t.Fatal("expected ErrGhostscriptNotFound, got:", err)
+	}
+}
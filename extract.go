@@ -0,0 +1,269 @@
+package p4p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+var (
+	resourcesRe  = regexp.MustCompile(`/Resources (\d+) 0 R`)
+	xobjectRefRe = regexp.MustCompile(`/(\S+) (\d+) 0 R`)
+	colorSpaceRe = regexp.MustCompile(`/ColorSpace (/\w+|\[[^\]]*\])`)
+	indexedRe    = regexp.MustCompile(`^\[/Indexed /DeviceRGB \d+ (\d+) 0 R\]$`)
+	smaskRe      = regexp.MustCompile(`/SMask (\d+) 0 R`)
+)
+
+// Returned by ExtractImages for documents encrypted with SetProtection.
+var ErrEncrypted = errors.New("p4p: extracting images from encrypted documents is not supported")
+
+// Extracts the images embedded in a PDF written by this package into dir, in the order they
+// first appear on the pages, and returns the paths of the written files. JPEGs are written as
+// they are embedded, all other images as PNGs, merging in their alpha channels and converting
+// CMYK images to RGB. Transparency from color key masks is not restored. Documents written with
+// SetObjectStreams are supported, encrypted ones are rejected with ErrEncrypted.
+func ExtractImages(r io.Reader, dir string) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsePDF(data)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(p.trailer, []byte("/Encrypt")) {
+		return nil, ErrEncrypted
+	}
+
+	var paths []string
+	seen := make(map[int]bool)
+	for _, page := range p.pages() {
+		xobjs := p.xobjects(page)
+		m := contentsRe.FindSubmatch(p.obj(page))
+		if m == nil {
+			continue
+		}
+		content, err := streamData(p.obj(atoi(m[1])))
+		if err != nil {
+			return paths, err
+		}
+		for _, do := range doRe.FindAllSubmatch(content, -1) {
+			n, ok := xobjs[string(do[1])]
+			if !ok || seen[n] || !bytes.Contains(p.obj(n), []byte("/Subtype /Image")) {
+				continue
+			}
+			seen[n] = true
+			img, ext, err := p.extractImage(n)
+			if err != nil {
+				return paths, fmt.Errorf("p4p: extracting image object %d: %w", n, err)
+			}
+			path := filepath.Join(dir, fmt.Sprintf("image%03d.%s", len(paths)+1, ext))
+			if err := os.WriteFile(path, img, 0o644); err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// Returns object n, or nil if there is no such object.
+func (p *pdfFile) obj(n int) []byte {
+	if n <= 0 || n >= len(p.objs) {
+		return nil
+	}
+	return p.objs[n]
+}
+
+// Returns the object numbers of the XObjects available to a page by name.
+func (p *pdfFile) xobjects(page int) map[string]int {
+	xobjs := make(map[string]int)
+	m := resourcesRe.FindSubmatch(p.obj(page))
+	if m == nil {
+		return xobjs
+	}
+	d := xobjectsRe.FindSubmatch(p.obj(atoi(m[1])))
+	if d == nil {
+		return xobjs
+	}
+	for _, ref := range xobjectRefRe.FindAllSubmatch(d[1], -1) {
+		xobjs[string(ref[1])] = atoi(ref[2])
+	}
+	return xobjs
+}
+
+// Returns the image XObject n as an image file and its extension.
+func (p *pdfFile) extractImage(n int) ([]byte, string, error) {
+	obj := p.obj(n)
+	dict, _ := splitStream(obj)
+	switch {
+	case bytes.Contains(dict, []byte("/Filter /DCTDecode")):
+		_, stream := splitStream(obj)
+		end := bytes.LastIndex(stream, []byte("\nendstream"))
+		if end < 0 {
+			return nil, "", errMalformedPDF
+		}
+		return stream[len("\nstream\n"):end], "jpg", nil
+	case bytes.Contains(dict, []byte("/Filter /FlateDecode")):
+		img, err := p.flateImage(n)
+		if err != nil {
+			return nil, "", err
+		}
+		sm := smaskRe.FindSubmatch(dict)
+		if sm == nil {
+			return img, "png", nil
+		}
+		alpha, err := p.flateImage(atoi(sm[1]))
+		if err != nil {
+			return nil, "", err
+		}
+		merged, err := mergeAlpha(img, alpha)
+		return merged, "png", err
+	}
+	return nil, "", errors.New("unsupported filter")
+}
+
+// Wraps the Flate encoded image XObject n into a PNG file.
+func (p *pdfFile) flateImage(n int) ([]byte, error) {
+	obj := p.obj(n)
+	dict, _ := splitStream(obj)
+	w, h := dictInt(dict, "Width"), dictInt(dict, "Height")
+	bpc := dictInt(dict, "BitsPerComponent")
+	cs := colorSpaceRe.FindSubmatch(dict)
+	if w <= 0 || h <= 0 || bpc <= 0 || cs == nil {
+		return nil, errMalformedPDF
+	}
+
+	var colorType, colors byte
+	var palette []byte
+	switch space := string(cs[1]); space {
+	case "/DeviceGray":
+		colorType, colors = 0, 1
+	case "/DeviceRGB":
+		colorType, colors = 2, 3
+	case "/DeviceCMYK":
+		return cmykPNG(obj, w, h, bpc)
+	default:
+		m := indexedRe.FindStringSubmatch(space)
+		if m == nil {
+			return nil, fmt.Errorf("unsupported color space %s", space)
+		}
+		var err error
+		if palette, err = streamData(p.obj(atoi([]byte(m[1])))); err != nil {
+			return nil, err
+		}
+		colorType, colors = 3, 1
+	}
+
+	data, err := streamData(obj)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(dict, []byte("/Predictor")) {
+		// PNG rows start with a filter type byte, which PDF only has with PNG predictors.
+		stride := (w*int(colors)*bpc + 7) / 8
+		rows := make([]byte, 0, h*(stride+1))
+		for y := 0; y+stride <= len(data) && y/stride < h; y += stride {
+			rows = append(rows, 0)
+			rows = append(rows, data[y:y+stride]...)
+		}
+		data = rows
+	}
+
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(w))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(h))
+	ihdr[8], ihdr[9] = byte(bpc), colorType
+	writePNGChunk(&b, "IHDR", ihdr)
+	if palette != nil {
+		writePNGChunk(&b, "PLTE", palette)
+	}
+	writePNGChunk(&b, "IDAT", compress(data))
+	writePNGChunk(&b, "IEND", nil)
+	return b.Bytes(), nil
+}
+
+// Converts the Flate encoded CMYK image XObject obj to an RGB PNG file, as PNG has no CMYK.
+func cmykPNG(obj []byte, w, h, bpc int) ([]byte, error) {
+	dict, _ := splitStream(obj)
+	if bpc != 8 || bytes.Contains(dict, []byte("/Predictor")) {
+		return nil, errors.New("unsupported CMYK encoding")
+	}
+	data, err := streamData(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4*w*h {
+		return nil, errMalformedPDF
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, &image.CMYK{Pix: data, Stride: 4 * w, Rect: image.Rect(0, 0, w, h)}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writePNGChunk(w *bytes.Buffer, typ string, data []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	io.WriteString(crc, typ)
+	crc.Write(data)
+	w.WriteString(typ)
+	w.Write(data)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// Combines a PNG with a grayscale PNG holding its alpha channel.
+func mergeAlpha(colorPNG, alphaPNG []byte) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(colorPNG))
+	if err != nil {
+		return nil, err
+	}
+	alpha, err := png.Decode(bytes.NewReader(alphaPNG))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	if alpha.Bounds() != b {
+		return nil, errMalformedPDF
+	}
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			c.A = color.GrayModel.Convert(alpha.At(x, y)).(color.Gray).Y
+			out.SetNRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Returns the value of an integer entry of a dictionary, or 0 if it is missing.
+func dictInt(dict []byte, key string) int {
+	m := regexp.MustCompile(`/` + key + ` (\d+)`).FindSubmatch(dict)
+	if m == nil {
+		return 0
+	}
+	return atoi(m[1])
+}
+
+func atoi(b []byte) int {
+	n, _ := strconv.Atoi(string(b))
+	return n
+}
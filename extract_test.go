@@ -0,0 +1,122 @@
+package p4p_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestExtractImages(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	paths := []string{"gophers/gopher.png", "gophers/gopher1.jpg", "gophers/gopher2.png"}
+	for _, path := range paths {
+		if err := g.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Placed a second time, but embedded only once.
+	if err := g.AddImageFile(paths[0], p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// Embedded as an indexed PNG, as the transparent entry rules out JPEG.
+	pal := color.Palette{color.NRGBA{}, color.NRGBA{R: 0x20, G: 0x80, B: 0xc0, A: 0xff}}
+	paletted := image.NewPaletted(image.Rect(0, 0, 30, 20), pal)
+	paletted.SetColorIndex(3, 4, 1)
+	if err := g.AddImage(paletted, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	out, err := p4p.ExtractImages(bytes.NewReader(writePDF(t, g)), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"image001.png", "image002.jpg", "image003.png", "image004.png"}
+	if len(out) != len(want) {
+		t.Fatal("expected 4 images, got:", out)
+	}
+	for i, path := range out {
+		if path != filepath.Join(dir, want[i]) {
+			t.Errorf("expected %s, got %s", want[i], path)
+		}
+	}
+
+	src := decodeFile(t, paths[0])
+	img := decodeFile(t, out[0])
+	if img.Bounds() != src.Bounds() {
+		t.Fatal("expected the original size, got:", img.Bounds())
+	}
+	for _, p := range []image.Point{{0, 0}, {95, 140}, {189, 282}} {
+		if a, b := color.NRGBAModel.Convert(img.At(p.X, p.Y)), color.NRGBAModel.Convert(src.At(p.X, p.Y)); a != b {
+			t.Errorf("pixel %v: expected %v, got %v", p, b, a)
+		}
+	}
+	if b := decodeFile(t, out[1]).Bounds(); b.Dx() != 316 || b.Dy() != 317 {
+		t.Error("expected the JPEG's size, got:", b)
+	}
+	if c := color.NRGBAModel.Convert(decodeFile(t, out[3]).At(3, 4)); c != pal[1] {
+		t.Error("expected the palette color, got:", c)
+	}
+}
+
+func TestExtractCMYKImage(t *testing.T) {
+	img := image.NewCMYK(image.Rect(0, 0, 30, 20))
+	img.SetCMYK(3, 4, color.CMYK{C: 0xff, Y: 0x80, K: 0x20})
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImage(img, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := p4p.ExtractImages(bytes.NewReader(writePDF(t, g)), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 {
+		t.Fatal("expected 1 image, got:", out)
+	}
+	got := decodeFile(t, out[0])
+	if got.Bounds() != img.Bounds() {
+		t.Fatal("expected the original size, got:", got.Bounds())
+	}
+	for _, p := range []image.Point{{0, 0}, {3, 4}} {
+		if a, b := color.RGBAModel.Convert(got.At(p.X, p.Y)), color.RGBAModel.Convert(img.At(p.X, p.Y)); a != b {
+			t.Errorf("pixel %v: expected %v, got %v", p, b, a)
+		}
+	}
+}
+
+func TestExtractImagesObjectStreams(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetObjectStreams(true)
+	for _, path := range []string{"gophers/gopher.png", "gophers/gopher1.jpg"} {
+		if err := g.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := p4p.ExtractImages(bytes.NewReader(writePDF(t, g)), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || filepath.Ext(out[0]) != ".png" || filepath.Ext(out[1]) != ".jpg" {
+		t.Fatal("expected a PNG and a JPEG, got:", out)
+	}
+	if img, src := decodeFile(t, out[0]), decodeFile(t, "gophers/gopher.png"); img.Bounds() != src.Bounds() {
+		t.Fatal("expected the original size, got:", img.Bounds())
+	}
+
+	g = p4p.NewGenerator(p4p.A4())
+	g.SetObjectStreams(true)
+	if err := g.SetProtection("user", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p4p.ExtractImages(bytes.NewReader(writePDF(t, g)), t.TempDir()); !errors.Is(err, p4p.ErrEncrypted) {
+		t.Fatal("expected ErrEncrypted, got:", err)
+	}
+}
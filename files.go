@@ -0,0 +1,140 @@
+package p4p
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math/bits"
+	"os"
+)
+
+// Progress of AddImageFiles, reported after each file.
+type Progress struct {
+	// Number of files processed so far, out of Total.
+	Done, Total int
+	Path        string
+	// Whether the file was skipped as a duplicate.
+	Skipped bool
+	// Why the file could not be added, if it failed.
+	Err error
+}
+
+// Sets a function called by AddImageFiles after each file. Passing nil removes it.
+func (g *Generator) SetProgressFunc(fn func(Progress)) {
+	g.progress = fn
+}
+
+// Which duplicate images AddImageFiles skips.
+type Dedup int
+
+const (
+	// Add every file.
+	KeepDuplicates Dedup = iota
+	// Skip files with the same content as a file added before.
+	SkipDuplicates
+	// Also skip images looking the same as an image added before, such as resized or re-encoded copies,
+	// by comparing perceptual hashes. Requires decoding every file.
+	SkipNearDuplicates
+)
+
+// Sets which duplicates AddImageFiles skips among all files it has added (default: KeepDuplicates).
+func (g *Generator) SetDeduplication(d Dedup) {
+	g.dedup = d
+}
+
+// Adds each file as a new page, in order. Files that fail to load don't stop the others; no page is
+// added for them, and their errors are returned joined with errors.Join.
+func (g *Generator) AddImageFiles(paths []string, opts ImageOptions) error {
+	var errs []error
+	for i, path := range paths {
+		skip, hashes, err := g.isDuplicate(path)
+		if err == nil && !skip {
+			if err = g.AddImageFile(path, opts); err == nil {
+				g.rememberFile(hashes)
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("p4p: adding %s: %w", path, err)
+			errs = append(errs, err)
+		}
+		if g.progress != nil {
+			g.progress(Progress{Done: i + 1, Total: len(paths), Path: path, Skipped: skip, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Maximum number of differing perceptual hash bits of near-duplicate images.
+const nearDuplicateDistance = 10
+
+// Hashes a file added by AddImageFiles is remembered by.
+type fileHashes struct {
+	// Whether the file was hashed at all, and its perceptual hash too.
+	ok, near bool
+	sum      [sha256.Size]byte
+	hash     uint64
+}
+
+// Reports whether the file duplicates one added before, and returns the hashes to remember it by once it
+// has been added.
+func (g *Generator) isDuplicate(path string) (bool, fileHashes, error) {
+	if g.dedup == KeepDuplicates {
+		return false, fileHashes{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fileHashes{}, err
+	}
+	hashes := fileHashes{ok: true, sum: sha256.Sum256(data)}
+	if g.seenFiles[hashes.sum] {
+		return true, hashes, nil
+	}
+	if g.dedup == SkipNearDuplicates {
+		img, err := g.decodeImage(bytes.NewReader(data))
+		if err != nil {
+			return false, hashes, err
+		}
+		hashes.near, hashes.hash = true, differenceHash(img)
+		for _, seen := range g.seenHashes {
+			if bits.OnesCount64(hashes.hash^seen) <= nearDuplicateDistance {
+				return true, hashes, nil
+			}
+		}
+	}
+	return false, hashes, nil
+}
+
+// Remembers an added file, so that later duplicates of it are skipped.
+func (g *Generator) rememberFile(hashes fileHashes) {
+	if !hashes.ok {
+		return
+	}
+	if g.seenFiles == nil {
+		g.seenFiles = make(map[[sha256.Size]byte]bool)
+	}
+	g.seenFiles[hashes.sum] = true
+	if hashes.near {
+		g.seenHashes = append(g.seenHashes, hashes.hash)
+	}
+}
+
+// Returns a 64-bit perceptual hash of the image: whether the brightness increases between horizontally
+// adjacent pixels of a 9x8 thumbnail.
+func differenceHash(img image.Image) uint64 {
+	small := resize(img, 9, 8)
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			l := color.GrayModel.Convert(small.NRGBAAt(x, y)).(color.Gray).Y
+			r := color.GrayModel.Convert(small.NRGBAAt(x+1, y)).(color.Gray).Y
+			h <<= 1
+			if r > l {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
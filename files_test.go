@@ -0,0 +1,127 @@
+package p4p_test
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddImageFilesDeduplication(t *testing.T) {
+	// A half size copy of gopher.png.
+	src := decodeFile(t, "gophers/gopher.png")
+	b := src.Bounds()
+	small := image.NewNRGBA(image.Rect(0, 0, b.Dx()/2, b.Dy()/2))
+	for y := 0; y < small.Rect.Dy(); y++ {
+		for x := 0; x < small.Rect.Dx(); x++ {
+			small.Set(x, y, src.At(b.Min.X+2*x, b.Min.Y+2*y))
+		}
+	}
+	copyPath := filepath.Join(t.TempDir(), "small.png")
+	f, err := os.Create(copyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, small); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	paths := []string{"gophers/gopher.png", "gophers/gopher2.png", "gophers/gopher.png", copyPath, "gophers/gopher1.jpg"}
+	for _, tc := range []struct {
+		dedup   p4p.Dedup
+		skipped []bool
+	}{
+		{p4p.KeepDuplicates, []bool{false, false, false, false, false}},
+		{p4p.SkipDuplicates, []bool{false, false, true, false, false}},
+		{p4p.SkipNearDuplicates, []bool{false, false, true, true, false}},
+	} {
+		g := p4p.NewGenerator(p4p.A4())
+		g.SetDeduplication(tc.dedup)
+		var progress []p4p.Progress
+		g.SetProgressFunc(func(p p4p.Progress) {
+			progress = append(progress, p)
+		})
+		if err := g.AddImageFiles(paths, p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		added := 0
+		for i, p := range progress {
+			if p.Done != i+1 || p.Total != len(paths) || p.Path != paths[i] || p.Skipped != tc.skipped[i] {
+				t.Fatal("wrong progress with dedup", tc.dedup, "got:", p)
+			}
+			if !p.Skipped {
+				added++
+			}
+		}
+		if len(progress) != len(paths) {
+			t.Fatal("expected progress for every file, got:", len(progress))
+		}
+		if pages := pageContents(t, writePDF(t, g)); len(pages) != added {
+			t.Fatal("expected", added, "pages with dedup", tc.dedup, "got:", len(pages))
+		}
+	}
+}
+
+func TestAddImageFilesErrors(t *testing.T) {
+	corrupt := filepath.Join(t.TempDir(), "corrupt.png")
+	if err := os.WriteFile(corrupt, []byte("\x89PNG\r\n\x1a\nnot really"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{"gophers/gopher.png", corrupt, "gophers/missing.jpg", "gophers/gopher1.jpg"}
+
+	g := p4p.NewGenerator(p4p.A4())
+	var failed []string
+	g.SetProgressFunc(func(p p4p.Progress) {
+		if p.Err != nil {
+			failed = append(failed, p.Path)
+		}
+	})
+	err := g.AddImageFiles(paths, p4p.ImageOptions{})
+	if err == nil {
+		t.Fatal("expected errors for the corrupt and missing files")
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected the missing file's error to be included, got:", err)
+	}
+	for _, path := range paths[1:3] {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("expected an error naming %s, got: %v", path, err)
+		}
+	}
+	if len(failed) != 2 || failed[0] != corrupt || failed[1] != paths[2] {
+		t.Fatal("expected progress to report the 2 failures, got:", failed)
+	}
+	// Only the files that loaded get pages.
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 pages, got:", len(pages))
+	}
+	for i, page := range pages {
+		if len(imageDraws(page)) != 1 {
+			t.Errorf("expected an image on page %d, got: %q", i+1, page)
+		}
+	}
+}
+
+func TestAddImageFilesDuplicateOfFailure(t *testing.T) {
+	for _, dedup := range []p4p.Dedup{p4p.SkipDuplicates, p4p.SkipNearDuplicates} {
+		g := p4p.NewGenerator(p4p.A4())
+		g.SetDeduplication(dedup)
+		g.SetMaxImageDimension(100, p4p.RejectOversized)
+		// Lifts the limit the first copy fails on.
+		g.SetProgressFunc(func(p4p.Progress) { g.SetMaxImageDimension(0, p4p.RejectOversized) })
+		err := g.AddImageFiles([]string{"gophers/gopher.png", "gophers/gopher.png"}, p4p.ImageOptions{})
+		if !errors.Is(err, p4p.ErrImageTooLarge) {
+			t.Fatal("expected the first copy to fail, got:", err)
+		}
+		if pages := pageContents(t, writePDF(t, g)); len(pages) != 1 {
+			t.Fatal("expected the second copy to be added with dedup", dedup, "got pages:", len(pages))
+		}
+	}
+}
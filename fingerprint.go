@@ -0,0 +1,47 @@
+package p4p
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"regexp"
+)
+
+var infoRe = regexp.MustCompile(`/Info (\d+) 0 R`)
+
+// Sets whether images added afterwards are hashed into a fingerprint of the document, which is written
+// into its information dictionary as P4PFingerprint (default: false). Enable it before adding images.
+func (g *Generator) SetFingerprint(enabled bool) {
+	g.fingerprint = enabled
+}
+
+// Returns the hex encoded SHA-256 fingerprint of the images added so far with fingerprinting enabled:
+// the hash of the SHA-256 hashes of the image data embedded in the document, in the order they were added.
+func (g *Generator) Fingerprint() string {
+	h := sha256.New()
+	for _, sum := range g.imageHashes {
+		h.Write(sum)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Returns r, hashing what is read from it into a new image hash if fingerprinting is enabled.
+func (g *Generator) hashImage(r io.Reader) (io.Reader, hash.Hash) {
+	if !g.fingerprint {
+		return r, nil
+	}
+	h := sha256.New()
+	return io.TeeReader(r, h), h
+}
+
+func (g *Generator) putFingerprint(p *pdfFile) {
+	if !g.fingerprint {
+		return
+	}
+	m := infoRe.FindSubmatch(p.trailer)
+	if m == nil {
+		return
+	}
+	p.extendDict(atoi(m[1]), "/P4PFingerprint "+pdfString(g.Fingerprint()))
+}
@@ -0,0 +1,39 @@
+package p4p_test
+
+import (
+	"regexp"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(paths ...string) string {
+		g := p4p.NewGenerator(p4p.A4())
+		g.SetFingerprint(true)
+		for _, path := range paths {
+			if err := g.AddImageFile(path, p4p.ImageOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		m := regexp.MustCompile(`/P4PFingerprint \(([0-9a-f]{64})\)`).FindSubmatch(writePDF(t, g))
+		if m == nil {
+			t.Fatal("output is missing the fingerprint")
+		}
+		if string(m[1]) != g.Fingerprint() {
+			t.Fatalf("expected fingerprint %s, got %s", g.Fingerprint(), m[1])
+		}
+		return string(m[1])
+	}
+
+	a := fingerprint("gophers/gopher.png", "gophers/gopher1.jpg")
+	if b := fingerprint("gophers/gopher.png", "gophers/gopher1.jpg"); a != b {
+		t.Fatal("expected the same fingerprint for the same images, got:", a, b)
+	}
+	if b := fingerprint("gophers/gopher.png", "gophers/gopher2.png"); a == b {
+		t.Fatal("expected the fingerprint to change with the images")
+	}
+	if b := fingerprint("gophers/gopher1.jpg", "gophers/gopher.png"); a == b {
+		t.Fatal("expected the fingerprint to change with the order of the images")
+	}
+}
@@ -0,0 +1,206 @@
+package p4p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// Where a photo was taken, from its EXIF GPS tags.
+type GeoLocation struct {
+	// Degrees north of the equator, negative in the southern hemisphere.
+	Latitude float64
+	// Degrees east of Greenwich, negative in the western hemisphere.
+	Longitude float64
+	// Meters above sea level, zero if unknown.
+	Altitude float64
+}
+
+var errMalformedExif = errors.New("p4p: malformed EXIF data")
+
+// Returns the GPS location recorded in the EXIF data of a JPEG image, or nil if there is none.
+func ReadGeoLocation(data []byte) (*GeoLocation, error) {
+	tiff, err := exifSegment(data)
+	if tiff == nil || err != nil {
+		return nil, err
+	}
+	return parseExifGPS(tiff)
+}
+
+// Returns the TIFF structure in the EXIF segment of a JPEG, or nil if it has none.
+func exifSegment(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		return nil, nil
+	}
+	const exifMarker = "Exif\x00\x00"
+	data = data[2:]
+	for len(data) >= 4 && data[0] == 0xff {
+		marker := data[1]
+		if marker == 0xda || marker == 0xd9 {
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[2:]))
+		if n < 2 || len(data) < 2+n {
+			return nil, errMalformedExif
+		}
+		seg := data[4 : 2+n]
+		if marker == 0xe1 && bytes.HasPrefix(seg, []byte(exifMarker)) {
+			return seg[len(exifMarker):], nil
+		}
+		data = data[2+n:]
+	}
+	return nil, nil
+}
+
+// Returns the byte order of a TIFF structure from its header, or nil if it has none.
+func tiffByteOrder(tiff []byte) binary.ByteOrder {
+	if len(tiff) < 8 {
+		return nil
+	}
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		return binary.LittleEndian
+	case "MM\x00*":
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// An image file directory of a TIFF structure.
+type tiffIFD struct {
+	tiff    []byte
+	order   binary.ByteOrder
+	entries map[uint16][]byte
+}
+
+func readIFD(tiff []byte, order binary.ByteOrder, off uint32) (*tiffIFD, error) {
+	if uint64(off)+2 > uint64(len(tiff)) {
+		return nil, errMalformedExif
+	}
+	n := int(order.Uint16(tiff[off:]))
+	if int(off)+2+12*n > len(tiff) {
+		return nil, errMalformedExif
+	}
+	ifd := &tiffIFD{tiff: tiff, order: order, entries: make(map[uint16][]byte, n)}
+	for i := 0; i < n; i++ {
+		e := tiff[int(off)+2+12*i:]
+		ifd.entries[order.Uint16(e)] = e[:12]
+	}
+	return ifd, nil
+}
+
+// Returns the value of a tag, or nil if it is missing or malformed.
+func (ifd *tiffIFD) value(tag uint16) []byte {
+	e := ifd.entries[tag]
+	if e == nil {
+		return nil
+	}
+	sizes := map[uint16]uint64{1: 1, 2: 1, 3: 2, 4: 4, 5: 8}
+	size := sizes[ifd.order.Uint16(e[2:])] * uint64(ifd.order.Uint32(e[4:]))
+	if size <= 4 {
+		// Small values are stored in the entry itself.
+		return e[8 : 8+size]
+	}
+	off := uint64(ifd.order.Uint32(e[8:]))
+	if off+size > uint64(len(ifd.tiff)) {
+		return nil
+	}
+	return ifd.tiff[off : off+size]
+}
+
+// Returns the RATIONAL values of a tag.
+func (ifd *tiffIFD) rationals(tag uint16) []float64 {
+	b := ifd.value(tag)
+	var r []float64
+	for ; len(b) >= 8; b = b[8:] {
+		num, den := ifd.order.Uint32(b), ifd.order.Uint32(b[4:])
+		if den == 0 {
+			return nil
+		}
+		r = append(r, float64(num)/float64(den))
+	}
+	return r
+}
+
+func parseExifGPS(tiff []byte) (*GeoLocation, error) {
+	order := tiffByteOrder(tiff)
+	if order == nil {
+		return nil, errMalformedExif
+	}
+	ifd0, err := readIFD(tiff, order, order.Uint32(tiff[4:]))
+	if err != nil {
+		return nil, err
+	}
+	const gpsIFDPointer = 0x8825
+	ptr := ifd0.value(gpsIFDPointer)
+	if len(ptr) != 4 {
+		return nil, nil
+	}
+	gps, err := readIFD(tiff, order, order.Uint32(ptr))
+	if err != nil {
+		return nil, err
+	}
+	// Degrees, minutes and seconds, with a hemisphere reference.
+	dms := func(refTag, tag uint16, negRef byte) (float64, bool) {
+		v := gps.rationals(tag)
+		if len(v) != 3 {
+			return 0, false
+		}
+		deg := v[0] + v[1]/60 + v[2]/3600
+		if ref := gps.value(refTag); len(ref) > 0 && ref[0] == negRef {
+			deg = -deg
+		}
+		return deg, true
+	}
+	loc := &GeoLocation{}
+	var okLat, okLon bool
+	loc.Latitude, okLat = dms(1, 2, 'S')
+	loc.Longitude, okLon = dms(3, 4, 'W')
+	if !okLat || !okLon {
+		return nil, nil
+	}
+	if alt := gps.rationals(6); len(alt) == 1 {
+		loc.Altitude = alt[0]
+		// Altitude reference 1 is below sea level.
+		if ref := gps.value(5); len(ref) == 1 && ref[0] == 1 {
+			loc.Altitude = -loc.Altitude
+		}
+	}
+	return loc, nil
+}
+
+// Records the GPS location of the image data, if any, for the pages added after page last, except a blank
+// page inserted before them.
+func (g *Generator) recordGeoLocation(last int, data []byte) {
+	// A missing or unreadable location doesn't affect the image itself.
+	loc, _ := ReadGeoLocation(data)
+	if loc == nil {
+		return
+	}
+	if g.geoLocations == nil {
+		g.geoLocations = make(map[int]GeoLocation)
+	}
+	for page := last + 1; page <= g.pdf.PageNo(); page++ {
+		if page != g.blankPage {
+			g.geoLocations[page] = *loc
+		}
+	}
+}
+
+// Writes the GPS location of each geotagged page into its page dictionary.
+func (g *Generator) putGeoLocations(p *pdfFile) {
+	for i, page := range p.pages() {
+		loc, ok := g.geoLocations[p.firstPage+i]
+		if !ok {
+			continue
+		}
+		p.extendDict(page, "/P4PGeoLocation <</Latitude "+pdfNumber(loc.Latitude)+
+			" /Longitude "+pdfNumber(loc.Longitude)+
+			" /Altitude "+pdfNumber(loc.Altitude)+">>")
+	}
+}
+
+func pdfNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
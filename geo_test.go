@@ -0,0 +1,102 @@
+package p4p_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Returns a JPEG image geotagged at 51°30'0" N, 0°7'30" W, 35 m above sea level.
+func jpegWithGPS(t *testing.T) []byte {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	be := binary.BigEndian
+	entry := func(tiff []byte, tag, typ uint16, count uint32, value []byte) []byte {
+		tiff = be.AppendUint16(be.AppendUint16(tiff, tag), typ)
+		return append(be.AppendUint32(tiff, count), append(value, make([]byte, 4-len(value))...)...)
+	}
+	const gpsIFD, gpsData = 26, 26 + 2 + 6*12 + 4
+	tiff := be.AppendUint32([]byte("MM\x00*"), 8)
+	tiff = be.AppendUint16(tiff, 1)
+	tiff = entry(tiff, 0x8825, 4, 1, be.AppendUint32(nil, gpsIFD))
+	tiff = be.AppendUint32(tiff, 0)
+	tiff = be.AppendUint16(tiff, 6)
+	tiff = entry(tiff, 1, 2, 2, []byte("N\x00"))
+	tiff = entry(tiff, 2, 5, 3, be.AppendUint32(nil, gpsData))
+	tiff = entry(tiff, 3, 2, 2, []byte("W\x00"))
+	tiff = entry(tiff, 4, 5, 3, be.AppendUint32(nil, gpsData+24))
+	tiff = entry(tiff, 5, 1, 1, []byte{0})
+	tiff = entry(tiff, 6, 5, 1, be.AppendUint32(nil, gpsData+48))
+	tiff = be.AppendUint32(tiff, 0)
+	for _, r := range [][2]uint32{{51, 1}, {30, 1}, {0, 1}, {0, 1}, {7, 1}, {30, 1}, {35, 1}} {
+		tiff = be.AppendUint32(be.AppendUint32(tiff, r[0]), r[1])
+	}
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xff, 0xe1}, be.AppendUint16(nil, uint16(len(seg)+2))...)
+	data := b.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(app1, seg...)...), data[2:]...)
+}
+
+func TestReadGeoLocation(t *testing.T) {
+	loc, err := p4p.ReadGeoLocation(jpegWithGPS(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc == nil || *loc != (p4p.GeoLocation{Latitude: 51.5, Longitude: -0.125, Altitude: 35}) {
+		t.Fatal("wrong location, got:", loc)
+	}
+	data, err := os.ReadFile("gophers/gopher1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc, err := p4p.ReadGeoLocation(data); loc != nil || err != nil {
+		t.Fatal("expected no location, got:", loc, err)
+	}
+}
+
+func TestGeoLocationMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geotagged.jpg")
+	os.WriteFile(path, jpegWithGPS(t), 0o644)
+
+	g := p4p.NewGenerator(p4p.A4())
+	for _, path := range []string{"gophers/gopher1.jpg", path} {
+		if err := g.AddImageFile(path, p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := writePDF(t, g)
+	want := "/P4PGeoLocation <</Latitude 51.5 /Longitude -0.125 /Altitude 35>>"
+	if bytes.Count(pdf, []byte(want)) != 1 {
+		t.Fatal("expected the location on one page")
+	}
+	pages := pageObjects(t, pdf)
+	if strings.Contains(pages[0].dict, want) || !strings.Contains(pages[1].dict, want) {
+		t.Fatal("location on the wrong page")
+	}
+}
+
+func TestGeoLocationStartOnRight(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geotagged.jpg")
+	os.WriteFile(path, jpegWithGPS(t), 0o644)
+
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetStartOnRight(true)
+	if err := g.AddImageFile(path, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	pages := pageObjects(t, pdf)
+	// The blank page facing the first one shows no image.
+	if len(pages) != 2 || strings.Contains(pages[0].dict, "/P4PGeoLocation") || !strings.Contains(pages[1].dict, "/P4PGeoLocation") {
+		t.Fatal("expected the location on the image's page only")
+	}
+}
@@ -0,0 +1,46 @@
+package p4p
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// Decodes the (possibly animated) GIF from r and adds each of its frames as a separate page, e.g. for a
+// flip-book. Frames only updating part of the picture are composited onto the previous frames according
+// to their disposal methods, so that each page shows the full picture.
+func (g *Generator) AddGIF(r io.Reader, opts ImageOptions) error {
+	anim, err := gif.DecodeAll(r)
+	if err != nil {
+		return err
+	}
+	bounds := image.Rect(0, 0, anim.Config.Width, anim.Config.Height)
+	canvas := image.NewRGBA(bounds)
+	for i, frame := range anim.Image {
+		disposal := byte(0)
+		if i < len(anim.Disposal) {
+			disposal = anim.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		page := image.NewRGBA(bounds)
+		copy(page.Pix, canvas.Pix)
+		if err := g.AddImage(page, opts); err != nil {
+			return err
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return nil
+}
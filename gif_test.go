@@ -0,0 +1,95 @@
+package p4p_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Returns a frame filling rect with c.
+func gifFrame(rect image.Rectangle, c color.Color) *image.Paletted {
+	return image.NewPaletted(rect, color.Palette{c})
+}
+
+func TestAddGIF(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0, 0, 0xff, 0xff}
+	green := color.RGBA{0, 0xff, 0, 0xff}
+	anim := &gif.GIF{
+		Image: []*image.Paletted{
+			gifFrame(image.Rect(0, 0, 32, 32), red),
+			// Delta frames; the blue square is removed again after it has been shown.
+			gifFrame(image.Rect(8, 8, 16, 16), blue),
+			gifFrame(image.Rect(16, 16, 24, 24), green),
+		},
+		Delay:    []int{10, 10, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalNone},
+		Config:   image.Config{Width: 32, Height: 32},
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddGIF(&buf, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	paths, err := p4p.ExtractImages(bytes.NewReader(writePDF(t, g)), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 {
+		t.Fatal("expected 3 pages, got:", len(paths))
+	}
+
+	for i, want := range []map[image.Point]color.RGBA{
+		{{4, 4}: red, {12, 12}: red, {20, 20}: red},
+		{{4, 4}: red, {12, 12}: blue, {20, 20}: red},
+		{{4, 4}: red, {12, 12}: red, {20, 20}: green},
+	} {
+		img := decodeFile(t, paths[i])
+		if img.Bounds().Size() != image.Pt(32, 32) {
+			t.Fatalf("page %d: expected full 32x32 picture, got %v", i+1, img.Bounds())
+		}
+		for pt, c := range want {
+			r, g, b, _ := img.At(pt.X, pt.Y).RGBA()
+			// Allow for JPEG compression.
+			if max(diff(r>>8, c.R), diff(g>>8, c.G), diff(b>>8, c.B)) > 0x20 {
+				t.Errorf("page %d: expected %v at %v, got %v", i+1, c, pt, img.At(pt.X, pt.Y))
+			}
+		}
+	}
+}
+
+func diff(a uint32, b uint8) uint32 {
+	if a > uint32(b) {
+		return a - uint32(b)
+	}
+	return uint32(b) - a
+}
+
+func TestAddGIFSingleFrame(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, decodeFile(t, "gophers/gopher.png"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddGIF(&buf, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 1 {
+		t.Fatal("expected 1 page, got:", len(pages))
+	}
+	// Like the still image itself, at its size.
+	if draws := imageDraws(pages[0]); len(draws) != 1 || draws[0].W != 190 || draws[0].H != 283 {
+		t.Fatal("expected one 190x283 image, got:", draws)
+	}
+}
@@ -2,4 +2,12 @@ module github.com/pic4pdf/lib-p4p
 
 go 1.21.3
 
-require github.com/jung-kurt/gofpdf v1.16.2
+require (
+	github.com/jung-kurt/gofpdf v1.16.2
+	golang.org/x/image v0.24.0
+)
+
+require (
+	github.com/phpdave11/gofpdi v1.0.7 // indirect
+	github.com/pkg/errors v0.8.1 // indirect
+)
@@ -0,0 +1,107 @@
+package p4p
+
+import (
+	"errors"
+	"image"
+)
+
+// A layout grid of equally sized columns and rows separated by gutters, for placing several images
+// consistently, e.g. with AddImagesAtRects.
+type Grid struct {
+	// Area covered by the grid, in points.
+	Rect    Rect
+	Columns int
+	Rows    int
+	// Space between adjacent columns and rows, in points.
+	Gutter float64
+}
+
+// Returns the rectangle spanning colspan columns and rowspan rows from the cell at col and row,
+// counted from 0 at the top left.
+func (g Grid) Cell(col, row, colspan, rowspan int) Rect {
+	cols, rows := max(g.Columns, 1), max(g.Rows, 1)
+	colW := (g.Rect.W - g.Gutter*float64(cols-1)) / float64(cols)
+	rowH := (g.Rect.H - g.Gutter*float64(rows-1)) / float64(rows)
+	return Rect{
+		X: g.Rect.X + float64(col)*(colW+g.Gutter),
+		Y: g.Rect.Y + float64(row)*(rowH+g.Gutter),
+		W: float64(colspan)*colW + float64(colspan-1)*g.Gutter,
+		H: float64(rowspan)*rowH + float64(rowspan-1)*g.Gutter,
+	}
+}
+
+// Sets the space between the cells of grids added with AddGrid, in points (default: 0).
+func (g *Generator) SetGridGutter(gutter float64) {
+	g.gridGutter = gutter
+}
+
+// How AddGrid lays out a last page with fewer images than cells; see SetLastPageLayout.
+type LastPageLayout int
+
+const (
+	// Fill the cells from the top left like on every other page, leaving the rest empty.
+	LastPageGrid LastPageLayout = iota
+	// Keep the size of the cells, but center each row of images and the rows on the page.
+	LastPageCentered
+	// Enlarge the cells to a grid of as few rows and columns as the images need, e.g. a single image
+	// covers the whole page.
+	LastPagePacked
+)
+
+// Sets how grids added with AddGrid lay out their last page if it is not full (default: LastPageGrid).
+func (g *Generator) SetLastPageLayout(layout LastPageLayout) {
+	g.lastPageLayout = layout
+}
+
+// Returns the rectangles of n images on a page of the grid, laid out as set with SetLastPageLayout if
+// they are fewer than the cells.
+func (g *Generator) gridCells(grid Grid, n int) []Rect {
+	rects := make([]Rect, n)
+	cols := grid.Columns
+	layout := g.lastPageLayout
+	if n >= grid.Columns*grid.Rows {
+		layout = LastPageGrid
+	}
+	switch layout {
+	case LastPageCentered:
+		cell := grid.Cell(0, 0, 1, 1)
+		rows := (n + cols - 1) / cols
+		y := grid.Rect.Y + (grid.Rect.H-float64(rows)*(cell.H+grid.Gutter)+grid.Gutter)/2
+		for i := range rects {
+			row, col := i/cols, i%cols
+			inRow := min(cols, n-row*cols)
+			x := grid.Rect.X + (grid.Rect.W-float64(inRow)*(cell.W+grid.Gutter)+grid.Gutter)/2
+			rects[i] = Rect{X: x + float64(col)*(cell.W+grid.Gutter), Y: y + float64(row)*(cell.H+grid.Gutter), W: cell.W, H: cell.H}
+		}
+		return rects
+	case LastPagePacked:
+		cols = min(cols, n)
+		grid.Columns, grid.Rows = cols, (n+cols-1)/cols
+	}
+	for i := range rects {
+		rects[i] = grid.Cell(i%cols, i/cols, 1, 1)
+	}
+	return rects
+}
+
+// Adds the images as contact sheets of rows by cols equally sized cells per page, in order from the top
+// left, starting a new page whenever a page is full. Each image is fitted to its cell; the cells cover the
+// page within opts.PrinterMargins and opts.Margins, separated by the gutter set with SetGridGutter. A last page
+// that is not full is laid out as set with SetLastPageLayout.
+func (g *Generator) AddGrid(imgs []image.Image, rows, cols int, opts ImageOptions) error {
+	if rows < 1 || cols < 1 {
+		return errors.New("p4p: rows and cols must be at least 1")
+	}
+	opts = g.optsInPoints(opts)
+	grid := Grid{Rect: opts.contentRect(g.pageRect()), Columns: cols, Rows: rows, Gutter: g.gridGutter}
+	cellOpts := opts
+	cellOpts.Mode = Fit
+	cellOpts.PrinterMargins, cellOpts.Margins, cellOpts.Gutter = Margins{}, Margins{}, 0
+	for start := 0; start < len(imgs); start += rows * cols {
+		page := imgs[start:min(start+rows*cols, len(imgs))]
+		if err := g.AddImagesAtRects(page, g.gridCells(grid, len(page)), BackToFront, cellOpts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
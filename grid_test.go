@@ -0,0 +1,100 @@
+package p4p_test
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestGridCell(t *testing.T) {
+	pg := p4p.A4()
+	grid := p4p.Grid{Rect: p4p.Rect{W: pg.W, H: pg.H}, Columns: 12, Rows: 4, Gutter: 12}
+	// Half the page, right of the center gutter, on the second and third rows.
+	rowH := (pg.H - 3*12) / 4
+	want := p4p.Rect{X: (pg.W + 12) / 2, Y: rowH + 12, W: (pg.W - 12) / 2, H: 2*rowH + 12}
+	got := grid.Cell(6, 1, 6, 2)
+	const eps = 1e-9
+	if math.Abs(got.X-want.X) > eps || math.Abs(got.Y-want.Y) > eps || math.Abs(got.W-want.W) > eps || math.Abs(got.H-want.H) > eps {
+		t.Fatal("wrong cell, got:", got, "want:", want)
+	}
+	if full := grid.Cell(0, 0, 12, 4); math.Abs(full.W-pg.W) > eps || math.Abs(full.H-pg.H) > eps {
+		t.Fatal("spanning all cells doesn't cover the grid, got:", full)
+	}
+}
+
+func TestAddGrid(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetGridGutter(12)
+	imgs := make([]image.Image, 5)
+	for i := range imgs {
+		imgs[i] = image.NewGray(image.Rect(0, 0, 100, 100))
+	}
+	m := p4p.Margins{Top: 36, Right: 36, Bottom: 36, Left: 36}
+	if err := g.AddGrid(imgs, 2, 2, p4p.ImageOptions{Mode: p4p.Fill, Margins: m}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 pages, got:", len(pages))
+	}
+	// Fitted to cells half the width within the margins, less the gutter.
+	const eps = 0.01
+	side := (p4p.A4().W - 2*36 - 12) / 2
+	for i, want := range [][]float64{{36, 36 + side + 12, 36, 36 + side + 12}, {36}} {
+		draws := imageDraws(pages[i])
+		if len(draws) != len(want) {
+			t.Fatal("expected", len(want), "images on page", i+1, "got:", len(draws))
+		}
+		for j, d := range draws {
+			if math.Abs(d.X-want[j]) > eps || math.Abs(d.W-side) > eps || math.Abs(d.H-side) > eps {
+				t.Fatal("wrong placement of image", j+1, "on page", i+1, "got:", d)
+			}
+		}
+	}
+	if err := g.AddGrid(imgs, 0, 2, p4p.ImageOptions{}); err == nil {
+		t.Fatal("expected an error with no rows")
+	}
+}
+
+func TestLastPageLayout(t *testing.T) {
+	pg := p4p.A4()
+	m := p4p.Margins{Top: 36, Right: 36, Bottom: 36, Left: 36}
+	side := (pg.W - 2*36 - 12) / 2
+	for _, tc := range []struct {
+		layout p4p.LastPageLayout
+		want   p4p.Rect
+	}{
+		// Cells half the width within the margins, less the gutter.
+		{p4p.LastPageGrid, p4p.Rect{X: 36, Y: pg.H - 36 - (pg.H-2*36-12)/4 - side/2, W: side, H: side}},
+		{p4p.LastPageCentered, p4p.Rect{X: (pg.W - side) / 2, Y: (pg.H - side) / 2, W: side, H: side}},
+		// A single cell covering the page within the margins.
+		{p4p.LastPagePacked, p4p.Rect{X: 36, Y: (pg.H - (pg.W - 2*36)) / 2, W: pg.W - 2*36, H: pg.W - 2*36}},
+	} {
+		g := p4p.NewGenerator(pg)
+		g.SetGridGutter(12)
+		g.SetLastPageLayout(tc.layout)
+		imgs := make([]image.Image, 5)
+		for i := range imgs {
+			imgs[i] = image.NewGray(image.Rect(0, 0, 100, 100))
+		}
+		if err := g.AddGrid(imgs, 2, 2, p4p.ImageOptions{Margins: m}); err != nil {
+			t.Fatal(err)
+		}
+		pages := pageContents(t, writePDF(t, g))
+		if len(pages) != 2 || len(imageDraws(pages[0])) != 4 {
+			t.Fatal("expected a full first page and a second page, got pages:", len(pages))
+		}
+		draws := imageDraws(pages[1])
+		if len(draws) != 1 {
+			t.Fatal("expected one image on page 2, got:", len(draws))
+		}
+		// Drawn rects are in PDF coordinates, with the origin at the bottom left.
+		const eps = 0.01
+		d := draws[0]
+		if math.Abs(d.X-tc.want.X) > eps || math.Abs(d.Y-tc.want.Y) > eps || math.Abs(d.W-tc.want.W) > eps || math.Abs(d.H-tc.want.H) > eps {
+			t.Fatalf("layout %d: expected the last image at %+v, got: %+v", tc.layout, tc.want, d)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package p4p
+
+// Edge of the page a binding gutter is on; see ImageOptions.Gutter.
+type GutterSide int
+
+const (
+	GutterLeft GutterSide = iota
+	GutterRight
+	// The left edge of odd pages and the right edge of even pages, as in a book opened at page one's
+	// right-hand side.
+	GutterInner
+)
+
+// Returns the number of the next page counted like the pages of a book, starting at a right-hand page one:
+// with SetStartOnRight, the blank page facing it isn't counted.
+func (g *Generator) nextBookPage() int {
+	if g.startOnRight {
+		return max(g.pdf.PageNo(), 1)
+	}
+	return g.pdf.PageNo() + 1
+}
+
+// Returns the options with the gutter added to the margins of the given page of the book.
+func (opts ImageOptions) withGutter(page int) ImageOptions {
+	if opts.Gutter <= 0 || opts.Mode == Fill {
+		return opts
+	}
+	side := opts.GutterSide
+	if side == GutterInner {
+		side = GutterLeft
+		if page%2 == 0 {
+			side = GutterRight
+		}
+	}
+	if side == GutterLeft {
+		opts.Margins.Left += opts.Gutter
+	} else {
+		opts.Margins.Right += opts.Gutter
+	}
+	opts.Gutter = 0
+	return opts
+}
@@ -0,0 +1,62 @@
+package p4p_test
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestGutter(t *testing.T) {
+	// Wide enough to fit the page width.
+	img := image.NewGray(image.Rect(0, 0, 1000, 100))
+	g := p4p.NewGenerator(p4p.A4())
+	for _, opts := range []p4p.ImageOptions{
+		{Mode: p4p.Fit},
+		{Mode: p4p.Fit, Gutter: 36},
+		{Mode: p4p.Fit, Gutter: 36, GutterSide: p4p.GutterRight},
+		{Mode: p4p.Fit, Gutter: 36, GutterSide: p4p.GutterInner},
+		{Mode: p4p.Fit, Gutter: 36, GutterSide: p4p.GutterInner},
+	} {
+		if err := g.AddImage(img, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := pageContents(t, writePDF(t, g))
+	for i, want := range []struct{ x, w float64 }{
+		{0, 595.28},
+		{36, 559.28},
+		{0, 559.28},
+		// Page 4 is a left-hand page bound on the right, page 5 a right-hand page bound on the left.
+		{0, 559.28},
+		{36, 559.28},
+	} {
+		draws := imageDraws(pages[i])
+		if len(draws) != 1 {
+			t.Fatal("expected one image on page", i+1, "got:", len(draws))
+		}
+		if d := draws[0]; math.Abs(d.X-want.x) > 0.01 || math.Abs(d.W-want.w) > 0.01 {
+			t.Fatal("wrong placement on page", i+1, "expected:", want, "got:", d)
+		}
+	}
+
+	// After the blank page facing it, page 2 is the right-hand page one of the book.
+	g = p4p.NewGenerator(p4p.A4())
+	g.SetStartOnRight(true)
+	for i := 0; i < 2; i++ {
+		if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit, Gutter: 36, GutterSide: p4p.GutterInner}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages = pageContents(t, writePDF(t, g))
+	if len(pages) != 3 || len(imageDraws(pages[0])) != 0 {
+		t.Fatal("expected a blank page and 2 image pages, got:", len(pages))
+	}
+	for i, x := range []float64{36, 0} {
+		draws := imageDraws(pages[i+1])
+		if len(draws) != 1 || math.Abs(draws[0].X-x) > 0.01 || math.Abs(draws[0].W-559.28) > 0.01 {
+			t.Fatal("wrong placement on page", i+2, "expected x:", x, "got:", draws)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package p4p
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// An ICC color profile embedded in a source image.
+type ICCProfile struct {
+	// Human-readable profile name, e.g. "sRGB IEC61966-2.1" or "Adobe RGB (1998)".
+	Description string
+	// Color space signature from the profile header, e.g. "RGB " or "CMYK".
+	ColorSpace string
+	Data       []byte
+}
+
+// Reports whether the profile describes sRGB, which PDF viewers assume for untagged images.
+func (p *ICCProfile) IsSRGB() bool {
+	return strings.Contains(strings.ToLower(p.Description), "srgb")
+}
+
+var errMalformedICC = errors.New("p4p: malformed ICC profile")
+
+// Returns the ICC profile embedded in PNG or JPEG data, or nil if there is none.
+func ReadICCProfile(data []byte) (*ICCProfile, error) {
+	var raw []byte
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		raw, err = pngICCProfile(data)
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		raw, err = jpegICCProfile(data)
+	}
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	return parseICCProfile(raw)
+}
+
+func pngICCProfile(data []byte) ([]byte, error) {
+	data = data[8:]
+	for len(data) >= 12 {
+		n := binary.BigEndian.Uint32(data)
+		typ := string(data[4:8])
+		if uint64(len(data)) < 12+uint64(n) {
+			return nil, errMalformedICC
+		}
+		chunk := data[8 : 8+n]
+		switch typ {
+		case "iCCP":
+			// Profile name, null separator, compression method, compressed profile.
+			i := bytes.IndexByte(chunk, 0)
+			if i < 0 || i+2 > len(chunk) {
+				return nil, errMalformedICC
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(chunk[i+2:]))
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			return io.ReadAll(zr)
+		case "IDAT", "IEND":
+			// iCCP must precede image data.
+			return nil, nil
+		}
+		data = data[12+n:]
+	}
+	return nil, nil
+}
+
+func jpegICCProfile(data []byte) ([]byte, error) {
+	const iccMarker = "ICC_PROFILE\x00"
+	// Profiles may be split across several APP2 segments, numbered from 1.
+	var parts [][]byte
+	data = data[2:]
+	for len(data) >= 4 && data[0] == 0xff {
+		marker := data[1]
+		if marker == 0xda || marker == 0xd9 {
+			// Start of scan or end of image; all metadata comes before.
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[2:]))
+		if n < 2 || len(data) < 2+n {
+			return nil, errMalformedICC
+		}
+		seg := data[4 : 2+n]
+		if marker == 0xe2 && len(seg) > len(iccMarker)+2 && string(seg[:len(iccMarker)]) == iccMarker {
+			seq, count := int(seg[len(iccMarker)]), int(seg[len(iccMarker)+1])
+			if parts == nil {
+				parts = make([][]byte, count)
+			}
+			if seq < 1 || seq > len(parts) {
+				return nil, errMalformedICC
+			}
+			parts[seq-1] = seg[len(iccMarker)+2:]
+		}
+		data = data[2+n:]
+	}
+	if parts == nil {
+		return nil, nil
+	}
+	return bytes.Join(parts, nil), nil
+}
+
+func parseICCProfile(raw []byte) (*ICCProfile, error) {
+	if len(raw) < 132 || string(raw[36:40]) != "acsp" {
+		return nil, errMalformedICC
+	}
+	p := &ICCProfile{
+		ColorSpace: string(raw[16:20]),
+		Data:       raw,
+	}
+	tags := int(binary.BigEndian.Uint32(raw[128:]))
+	for i := 0; i < tags && 132+12*(i+1) <= len(raw); i++ {
+		tag := raw[132+12*i:]
+		if string(tag[:4]) != "desc" {
+			continue
+		}
+		off, size := binary.BigEndian.Uint32(tag[4:]), binary.BigEndian.Uint32(tag[8:])
+		if uint64(off)+uint64(size) > uint64(len(raw)) {
+			return nil, errMalformedICC
+		}
+		p.Description = iccText(raw[off : off+size])
+	}
+	return p, nil
+}
+
+// Decodes an ICC textDescriptionType (v2) or multiLocalizedUnicodeType (v4) tag, using the first record of the latter.
+func iccText(b []byte) string {
+	if len(b) < 12 {
+		return ""
+	}
+	switch string(b[:4]) {
+	case "desc":
+		n := binary.BigEndian.Uint32(b[8:])
+		if uint64(12+n) > uint64(len(b)) {
+			return ""
+		}
+		return strings.TrimRight(string(b[12:12+n]), "\x00")
+	case "mluc":
+		if binary.BigEndian.Uint32(b[8:]) == 0 || len(b) < 28 {
+			return ""
+		}
+		n, off := binary.BigEndian.Uint32(b[20:]), binary.BigEndian.Uint32(b[24:])
+		if uint64(off)+uint64(n) > uint64(len(b)) {
+			return ""
+		}
+		u := make([]uint16, n/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(b[int(off)+2*i:])
+		}
+		return string(utf16.Decode(u))
+	}
+	return ""
+}
+
+// Sets a function called when an image embeds an ICC profile other than sRGB. The profile is not carried
+// over into the PDF, so the image's colors will be shifted. Source is the image's path or URL.
+func (g *Generator) SetColorProfileWarning(fn func(source string, profile *ICCProfile)) {
+	g.colorProfileWarning = fn
+}
+
+// Invokes the color profile warning if the image data embeds a non-sRGB profile.
+func (g *Generator) checkColorProfile(source string, data []byte) {
+	if g.colorProfileWarning == nil {
+		return
+	}
+	// Images with unreadable profiles are still usable, so they just don't warn.
+	if p, _ := ReadICCProfile(data); p != nil && !p.IsSRGB() {
+		g.colorProfileWarning(source, p)
+	}
+}
@@ -0,0 +1,112 @@
+package p4p_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Returns a minimal ICC profile with only a v2 description tag.
+func iccProfile(desc string) []byte {
+	tag := append([]byte("desc\x00\x00\x00\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(desc)+1))...)
+	tag = append(append(tag, desc...), 0)
+	p := make([]byte, 128)
+	copy(p[16:], "RGB ")
+	copy(p[36:], "acsp")
+	p = binary.BigEndian.AppendUint32(p, 1)
+	p = append(p, "desc"...)
+	p = binary.BigEndian.AppendUint32(p, 128+4+12)
+	p = binary.BigEndian.AppendUint32(p, uint32(len(tag)))
+	p = append(p, tag...)
+	binary.BigEndian.PutUint32(p, uint32(len(p)))
+	return p
+}
+
+// Returns a PNG image with an embedded ICC profile.
+func pngWithProfile(t *testing.T, profile []byte) []byte {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewNRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(profile)
+	zw.Close()
+	chunk := append([]byte("iCCPtest\x00\x00"), z.Bytes()...)
+	var c []byte
+	c = binary.BigEndian.AppendUint32(c, uint32(len(chunk)-4))
+	c = append(c, chunk...)
+	c = binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE(chunk))
+	// Insert after the signature and IHDR chunk.
+	data := b.Bytes()
+	const ihdrEnd = 8 + 8 + 13 + 4
+	return append(append(append([]byte{}, data[:ihdrEnd]...), c...), data[ihdrEnd:]...)
+}
+
+// Returns a JPEG image with an embedded ICC profile.
+func jpegWithProfile(t *testing.T, profile []byte) []byte {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	seg := append([]byte("ICC_PROFILE\x00\x01\x01"), profile...)
+	app2 := append([]byte{0xff, 0xe2}, binary.BigEndian.AppendUint16(nil, uint16(len(seg)+2))...)
+	data := b.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(app2, seg...)...), data[2:]...)
+}
+
+func TestReadICCProfile(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"png":  pngWithProfile(t, iccProfile("Adobe RGB (1998)")),
+		"jpeg": jpegWithProfile(t, iccProfile("Adobe RGB (1998)")),
+	} {
+		p, err := p4p.ReadICCProfile(data)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if p == nil || p.Description != "Adobe RGB (1998)" || p.ColorSpace != "RGB " || p.IsSRGB() {
+			t.Fatal(name, "wrong profile, got:", p)
+		}
+	}
+	data, err := os.ReadFile("gophers/gopher1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := p4p.ReadICCProfile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || !p.IsSRGB() {
+		t.Fatal("expected sRGB profile")
+	}
+}
+
+func TestColorProfileWarning(t *testing.T) {
+	dir := t.TempDir()
+	adobe, srgb := filepath.Join(dir, "adobe.png"), filepath.Join(dir, "srgb.png")
+	os.WriteFile(adobe, pngWithProfile(t, iccProfile("Adobe RGB (1998)")), 0o644)
+	os.WriteFile(srgb, pngWithProfile(t, iccProfile("sRGB IEC61966-2.1")), 0o644)
+
+	g := p4p.NewGenerator(p4p.A4())
+	var warned []string
+	g.SetColorProfileWarning(func(source string, profile *p4p.ICCProfile) {
+		warned = append(warned, source)
+	})
+	for _, path := range []string{adobe, srgb, "gophers/gopher.png"} {
+		if err := g.AddImageFile(path, p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(warned) != 1 || warned[0] != adobe {
+		t.Fatal("expected a warning for the Adobe RGB image only, got:", warned)
+	}
+}
@@ -0,0 +1,35 @@
+package p4p
+
+import (
+	"encoding/json"
+	"image"
+	"io"
+)
+
+// Where an image was placed, as listed by WriteIndex.
+type IndexEntry struct {
+	Page int `json:"page"`
+	// File path or URL the image was read from, empty for images added from memory.
+	// Video frames are listed as the video's path followed by "#" and the frame number.
+	Source string `json:"source,omitempty"`
+	// Placement of the whole image on the page in points, including any part cropped away.
+	Rect Rect `json:"rect"`
+	// Part of the image visible on the page in pixels, if cropped.
+	Crop *image.Rectangle `json:"crop,omitempty"`
+
+	// Name the image is registered under, and the size in pixels Rect and Crop refer to.
+	name string
+	size image.Point
+}
+
+// Writes a JSON sidecar index listing the source, page and placement of every image added so far,
+// for tooling that needs to map the document back to its inputs.
+func (g *Generator) WriteIndex(w io.Writer) error {
+	entries := g.index
+	if entries == nil {
+		entries = []IndexEntry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
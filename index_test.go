@@ -0,0 +1,71 @@
+package p4p_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestWriteIndex(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	paths := []string{"gophers/gopher.png", "gophers/gopher1.jpg"}
+	for _, path := range paths {
+		if err := g.AddImageFile(path, p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AddImage(decodeFile(t, "gophers/gopher2.png"), p4p.ImageOptions{Mode: p4p.Fill}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := g.WriteIndex(&b); err != nil {
+		t.Fatal(err)
+	}
+	var entries []p4p.IndexEntry
+	if err := json.Unmarshal(b.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatal("expected 3 entries, got:", len(entries))
+	}
+
+	pages := pageContents(t, writePDF(t, g))
+	pgH := p4p.A4().H
+	for i, e := range entries {
+		if e.Page != i+1 {
+			t.Errorf("entry %d: expected page %d, got %d", i, i+1, e.Page)
+		}
+		if i < len(paths) && e.Source != paths[i] {
+			t.Errorf("entry %d: expected source %q, got %q", i, paths[i], e.Source)
+		}
+		// Drawn rects are in PDF coordinates, with the origin at the bottom left.
+		d := imageDraws(pages[i])[0]
+		want := p4p.Rect{X: d.X, Y: pgH - d.Y - d.H, W: d.W, H: d.H}
+		if c := e.Crop; c != nil {
+			// Only the visible part of the 245x300 pixel image is embedded and drawn, including the
+			// partly visible pixels.
+			scale := e.Rect.W / 245
+			if c.Dy() != 300 || d.W < float64(c.Dx())*scale-0.01 || d.W > float64(c.Dx()+1)*scale+0.01 {
+				t.Errorf("entry %d: expected the drawn image to be cropped to %v, got %+v", i, c, d)
+			}
+			want.X -= float64(c.Min.X) * scale
+			want.W = 245 * scale
+		}
+		for _, v := range [][2]float64{{e.Rect.X, want.X}, {e.Rect.Y, want.Y}, {e.Rect.W, want.W}, {e.Rect.H, want.H}} {
+			if math.Abs(v[0]-v[1]) > 0.01 {
+				t.Errorf("entry %d: expected rect %+v, got %+v", i, want, e.Rect)
+				break
+			}
+		}
+	}
+	if entries[2].Source != "" {
+		t.Error("expected no source for an in-memory image, got:", entries[2].Source)
+	}
+	if entries[0].Crop != nil || entries[2].Crop == nil {
+		t.Error("expected only the filled image to be cropped")
+	}
+}
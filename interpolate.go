@@ -0,0 +1,46 @@
+package p4p
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Records the /Interpolate flag of a registered image. Identical images share one embedded
+// object, which keeps the flag it was first registered with.
+func (g *Generator) setInterpolate(info *gofpdf.ImageInfoType, interpolate bool) error {
+	id, err := imageID(info)
+	if err != nil {
+		return err
+	}
+	if g.interpolate == nil {
+		g.interpolate = make(map[string]bool)
+	}
+	if _, ok := g.interpolate[id]; !ok {
+		g.interpolate[id] = interpolate
+	}
+	return nil
+}
+
+// Returns the ID gofpdf derives an image's XObject name from, which it doesn't expose.
+func imageID(info *gofpdf.ImageInfoType) (string, error) {
+	b, err := info.GobEncode()
+	return fmt.Sprintf("%x", sha1.Sum(b)), err
+}
+
+// Adds the /Interpolate flags to the image objects, found through the resource dictionary.
+func (g *Generator) putInterpolate(p *pdfFile) {
+	for id, interpolate := range g.interpolate {
+		re := regexp.MustCompile(`/I` + id + ` (\d+) 0 R`)
+		for _, obj := range p.objs {
+			if m := re.FindSubmatch(obj); m != nil {
+				n, _ := strconv.Atoi(string(m[1]))
+				p.extendDict(n, "/Interpolate "+strconv.FormatBool(interpolate))
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package p4p_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestInterpolate(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	on, off := true, false
+	for _, img := range []struct {
+		path        string
+		interpolate *bool
+	}{
+		{"gophers/gopher.png", &on},
+		{"gophers/gopher1.jpg", &off},
+		{"gophers/gopher2.png", nil},
+	} {
+		if err := g.AddImageFile(img.path, p4p.ImageOptions{Interpolate: img.interpolate}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := writePDF(t, g)
+	for i, page := range pageContents(t, pdf) {
+		name := regexp.MustCompile(`/(I\w+) Do`).FindStringSubmatch(page)[1]
+		ref := regexp.MustCompile(`/` + name + ` (\d+) 0 R`).FindSubmatch(pdf)
+		obj := regexp.MustCompile(`(?s)\n` + string(ref[1]) + ` 0 obj\n(.*?)stream\n`).FindSubmatch(pdf)
+		var want []byte
+		switch i {
+		case 0:
+			want = []byte("/Interpolate true")
+		case 1:
+			want = []byte("/Interpolate false")
+		}
+		if got := regexp.MustCompile(`/Interpolate \w+`).Find(obj[1]); !bytes.Equal(got, want) {
+			t.Fatalf("wrong flag on image %d, got: %q, want: %q", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package p4p
+
+// Identifies an optional content layer added with AddLayer. The zero value is no layer.
+type LayerID int
+
+// Adds an optional content layer, e.g. for annotations or watermarks, which can be toggled in the
+// viewer's layer panel. Assign images to it with ImageOptions.Layer.
+func (g *Generator) AddLayer(name string) LayerID {
+	return g.addLayer(name, true)
+}
+
+func (g *Generator) addLayer(name string, visible bool) LayerID {
+	g.pdf.OpenLayerPane()
+	return LayerID(g.pdf.AddLayer(name, visible) + 1)
+}
+
+// Starts drawing on the layer, if any. Layers can't be nested.
+func (g *Generator) beginLayer(l LayerID) {
+	if l != 0 {
+		g.pdf.BeginLayer(int(l) - 1)
+	}
+}
+
+func (g *Generator) endLayer(l LayerID) {
+	if l != 0 {
+		g.pdf.EndLayer()
+	}
+}
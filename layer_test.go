@@ -0,0 +1,36 @@
+package p4p_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddLayer(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.AddLayer("Photos")
+	notes := g.AddLayer("Notes")
+	if err := g.AddImage(decodeFile(t, "gophers/gopher.png"), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImage(decodeFile(t, "gophers/gopher2.png"), p4p.ImageOptions{Mode: p4p.Fit, Layer: notes}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	if n := bytes.Count(pdf, []byte("/Type /OCG")); n != 2 {
+		t.Fatal("expected 2 optional content groups, got:", n)
+	}
+	if !bytes.Contains(pdf, []byte("/OCProperties")) {
+		t.Fatal("missing optional content properties")
+	}
+	pages := pageContents(t, pdf)
+	if strings.Contains(pages[0], "BDC") {
+		t.Fatal("image without layer assigned to a layer")
+	}
+	start, end := strings.Index(pages[1], "/OC /OC1 BDC"), strings.LastIndex(pages[1], "EMC")
+	if start < 0 || end < start || len(imageDraws(pages[1][start:end])) != 1 {
+		t.Fatal("image not assigned to layer, got:", pages[1])
+	}
+}
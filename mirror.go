@@ -0,0 +1,41 @@
+package p4p
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var mediaBoxRe = regexp.MustCompile(`/MediaBox \[0 0 ([\d.]+) [\d.]+\]`)
+
+// Sets whether the content of every page is mirrored horizontally when the document is written, e.g.
+// for transfer printing (default: false). Unlike per-image flips, this mirrors everything on the page.
+// Link areas are left in place.
+func (g *Generator) SetMirrorOutput(enabled bool) {
+	g.mirrorOutput = enabled
+}
+
+func (g *Generator) putMirror(p *pdfFile) {
+	if !g.mirrorOutput {
+		return
+	}
+	for _, page := range p.pages() {
+		// Pages of the default size inherit their MediaBox from the page tree root.
+		box := mediaBoxRe.FindSubmatch(p.obj(page))
+		if box == nil {
+			box = mediaBoxRe.FindSubmatch(p.obj(1))
+		}
+		m := contentsRe.FindSubmatch(p.obj(page))
+		if box == nil || m == nil {
+			continue
+		}
+		w, _ := strconv.ParseFloat(string(box[1]), 64)
+		n := atoi(m[1])
+		content, err := streamData(p.obj(n))
+		if err != nil {
+			continue
+		}
+		mirrored := compress(append([]byte(fmt.Sprintf("q -1 0 0 1 %.2f 0 cm\n", w)), append(content, "\nQ"...)...))
+		p.set(n, fmt.Sprintf("<</Filter /FlateDecode /Length %d>>\nstream\n%s\nendstream", len(mirrored), mirrored))
+	}
+}
@@ -0,0 +1,34 @@
+package p4p_test
+
+import (
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestMirrorOutput(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetMirrorOutput(true)
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Caption: "Gopher"}); err != nil {
+		t.Fatal(err)
+	}
+	// gopher2.png is tagged with 600 DPI, making its page 29.40pt wide.
+	g.SetPageSizeFromDPI(true)
+	if err := g.AddImageFile("gophers/gopher2.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 pages, got:", len(pages))
+	}
+	for i, width := range []string{"595.28", "29.40"} {
+		prefix := "q -1 0 0 1 " + width + " 0 cm\n"
+		if !strings.HasPrefix(pages[i], prefix) || !strings.HasSuffix(pages[i], "\nQ") {
+			t.Fatal("expected page", i+1, "to be wrapped in a mirroring transform, got:", pages[i])
+		}
+		if !strings.Contains(pages[i], " Do Q") {
+			t.Fatal("expected the page content to be kept on page", i+1)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package p4p
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Converts all images set or added afterwards, including header and footer images, to grayscale and draws
+// all colors, e.g. of backgrounds, tints, page numbers, navigation buttons and test pages, in their gray
+// equivalents, for cheap printing. Imported vector pages are left as they are.
+func (g *Generator) SetMonochrome(enabled bool) {
+	g.monochrome = enabled
+}
+
+// Returns c in the color it is drawn in, i.e. its gray equivalent in monochrome documents.
+func (g *Generator) color(c color.Color) color.Color {
+	if g.monochrome {
+		return color.GrayModel.Convert(c)
+	}
+	return c
+}
+
+// Converts the image to grayscale in monochrome documents. Images that already are grayscale are left
+// unchanged, and kept as they were encoded.
+func (g *Generator) toMonochrome(p *pendingImage) error {
+	if !g.monochrome {
+		return nil
+	}
+	if _, ok, err := p.size(); err != nil || !ok {
+		return err
+	}
+	img, err := g.decodePending(p)
+	if err != nil {
+		return err
+	}
+	switch img.(type) {
+	case *image.Gray, *image.Gray16:
+		return nil
+	}
+	p.replace(grayscale(img))
+	return nil
+}
+
+// Returns the image in shades of gray. Images with transparency keep their alpha channel, which
+// image/png can only write along with RGB colors.
+func grayscale(img image.Image) image.Image {
+	switch img.(type) {
+	case *image.Gray, *image.Gray16:
+		return img
+	}
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		dst := image.NewGray(img.Bounds())
+		draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+		return dst
+	}
+	b := img.Bounds()
+	dst := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			gray := color.GrayModel.Convert(color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}).(color.Gray).Y
+			dst.SetNRGBA(x, y, color.NRGBA{R: gray, G: gray, B: gray, A: c.A})
+		}
+	}
+	return dst
+}
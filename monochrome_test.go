@@ -0,0 +1,108 @@
+package p4p_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestMonochrome(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		if i%4 == 0 || i%4 == 3 {
+			img.Pix[i] = 0xff
+		}
+	}
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetMonochrome(true)
+	// A red image on a page with a blue border around it.
+	opts := p4p.ImageOptions{Mode: p4p.Fit, Margins: p4p.Margins{Top: 36, Bottom: 36, Left: 36, Right: 36}}
+	g.SetPageBackgroundColor(color.RGBA{B: 0xff, A: 0xff})
+	header := image.NewRGBA(image.Rect(0, 0, 100, 10))
+	for i := range header.Pix {
+		if i%4 == 0 || i%4 == 3 {
+			header.Pix[i] = 0xff
+		}
+	}
+	if err := g.SetHeaderImage(header, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImage(img, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{BackgroundColor: color.RGBA{R: 0xff, A: 0xff}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddTestPage(p4p.Millimeter); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	for i, content := range pageContents(t, pdf) {
+		if strings.Contains(content, " RG\n") {
+			t.Fatal("expected only gray stroke colors on page", i+1, "got:", content)
+		}
+		if strings.Contains(content, " rg\n") {
+			t.Fatal("expected only gray colors on page", i+1, "got:", content)
+		}
+		if !strings.Contains(content, " g\n0.00 841.89 595.28 -841.89 re f") {
+			t.Fatal("expected a gray page background on page", i+1, "got:", content)
+		}
+	}
+
+	paths, err := p4p.ExtractImages(strings.NewReader(string(pdf)), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 {
+		t.Fatal("expected 3 images, got:", len(paths))
+	}
+	for _, path := range paths {
+		img := decodeFile(t, path)
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				if r>>8 != g>>8 || g>>8 != b>>8 {
+					t.Fatalf("expected %s to be grayscale, got %v at %d,%d", path, img.At(x, y), x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestMonochromeKeepsGrayImages(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Already gray, the JPEG is embedded as it is rather than encoded once more.
+	extracted := func(monochrome bool) []byte {
+		g := p4p.NewGenerator(p4p.A4())
+		g.SetMonochrome(monochrome)
+		if err := g.AddImageBytes(b.Bytes(), p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		paths, err := p4p.ExtractImages(bytes.NewReader(writePDF(t, g)), t.TempDir())
+		if err != nil || len(paths) != 1 {
+			t.Fatal("expected 1 image, got:", paths, err)
+		}
+		data, err := os.ReadFile(paths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	if !bytes.Equal(extracted(true), extracted(false)) {
+		t.Fatal("expected the gray JPEG to be embedded unchanged")
+	}
+}
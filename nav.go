@@ -0,0 +1,65 @@
+package p4p
+
+import (
+	"image/color"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Appearance of the navigation buttons; see SetNavigationButtons.
+type NavOptions struct {
+	// Width and height of each button in points (default: 24).
+	Size float64
+	// Distance of the buttons from the bottom and side edges of the page in points (default: 12).
+	Margin float64
+	// Fill color of the buttons (default: gray).
+	Color color.Color
+}
+
+// Adds previous/next buttons to the bottom corners of every page added afterwards, linking to the
+// adjacent pages. The first page has no previous and the last page no next button, and the blank page
+// inserted with SetStartOnRight has none.
+func (g *Generator) SetNavigationButtons(opts NavOptions) {
+	if opts.Size <= 0 {
+		opts.Size = 24
+	}
+	if opts.Margin <= 0 {
+		opts.Margin = 12
+	}
+	if opts.Color == nil {
+		opts.Color = color.Gray{Y: 0x80}
+	}
+	g.nav = &opts
+}
+
+// Draws a button on the current page linking to the next page, which must be added right afterwards.
+// Returns the link to point at the next page once it exists, or 0 if no button was drawn.
+func (g *Generator) drawNextButton() int {
+	// The blank page inserted with SetStartOnRight isn't navigated from or to.
+	if g.nav == nil || g.pdf.PageNo() == 0 || g.pdf.PageNo() == g.blankPage {
+		return 0
+	}
+	w, h := g.pdf.GetPageSize()
+	s, m := g.nav.Size, g.nav.Margin
+	x, y := w-m-s, h-m-s
+	setFillColor(g.pdf, g.color(g.nav.Color))
+	g.pdf.Polygon([]gofpdf.PointType{{X: x, Y: y}, {X: x, Y: y + s}, {X: x + s, Y: y + s/2}}, "F")
+	link := g.pdf.AddLink()
+	g.pdf.Link(x, y, s, s, link)
+	return link
+}
+
+// Draws a button on the current page linking to the previous page.
+func (g *Generator) drawPrevButton() {
+	if g.nav == nil || g.pdf.PageNo() < 2 || g.pdf.PageNo()-1 == g.blankPage {
+		return
+	}
+	_, h := g.pdf.GetPageSize()
+	s, m := g.nav.Size, g.nav.Margin
+	x, y := m, h-m-s
+	setFillColor(g.pdf, g.color(g.nav.Color))
+	g.pdf.Polygon([]gofpdf.PointType{{X: x + s, Y: y}, {X: x + s, Y: y + s}, {X: x, Y: y + s/2}}, "F")
+	link := g.pdf.AddLink()
+	g.pdf.SetLink(link, 0, g.pdf.PageNo()-1)
+	g.pdf.Link(x, y, s, s, link)
+}
@@ -0,0 +1,89 @@
+package p4p_test
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestNavigationButtons(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetNavigationButtons(p4p.NavOptions{})
+	for i := 0; i < 3; i++ {
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := writePDF(t, g)
+	pages := pageObjects(t, pdf)
+	index := make(map[int]int)
+	for i, page := range pages {
+		index[page.num] = i
+	}
+	destRe := regexp.MustCompile(`/Subtype /Link .*?/Dest \[(\d+) 0 R`)
+	want := [][]int{{1}, {0, 2}, {1}}
+	for i, page := range pages {
+		var dests []int
+		for _, m := range destRe.FindAllStringSubmatch(page.dict, -1) {
+			n, _ := strconv.Atoi(m[1])
+			dests = append(dests, index[n])
+		}
+		if len(dests) != len(want[i]) {
+			t.Fatal("wrong links on page", i+1, "got:", dests)
+		}
+		for _, d := range dests {
+			if d != i-1 && d != i+1 {
+				t.Fatal("link to non-adjacent page on page", i+1, "got:", dests)
+			}
+		}
+	}
+}
+
+func TestNavigationButtonsStartOnRight(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetNavigationButtons(p4p.NavOptions{})
+	g.SetStartOnRight(true)
+	for i := 0; i < 2; i++ {
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := pageObjects(t, writePDF(t, g))
+	if len(pages) != 3 {
+		t.Fatal("expected a blank page and 2 image pages, got:", len(pages))
+	}
+	destRe := regexp.MustCompile(`/Subtype /Link .*?/Dest \[(\d+) 0 R`)
+	// No links on the blank page, and none to it.
+	for i, want := range []int{-1, pages[2].num, pages[1].num} {
+		m := destRe.FindAllStringSubmatch(pages[i].dict, -1)
+		if want < 0 && len(m) != 0 || want >= 0 && (len(m) != 1 || m[0][1] != strconv.Itoa(want)) {
+			t.Fatal("wrong links on page", i+1, "got:", m)
+		}
+	}
+}
+
+type pageObject struct {
+	num  int
+	dict string
+}
+
+// Returns the object number and dictionary of each page in page order.
+func pageObjects(t *testing.T, pdf []byte) []pageObject {
+	t.Helper()
+	kids := regexp.MustCompile(`/Kids \[([^\]]*)\]`).FindSubmatch(pdf)
+	if kids == nil {
+		t.Fatal("missing page tree")
+	}
+	var pages []pageObject
+	for _, m := range regexp.MustCompile(`(\d+) 0 R`).FindAllSubmatch(kids[1], -1) {
+		dict := regexp.MustCompile(`(?s)\n` + string(m[1]) + ` 0 obj\n(.*?)\nendobj`).FindSubmatch(pdf)
+		if dict == nil {
+			t.Fatal("missing page object", string(m[1]))
+		}
+		n, _ := strconv.Atoi(string(m[1]))
+		pages = append(pages, pageObject{n, string(dict[1])})
+	}
+	return pages
+}
@@ -0,0 +1,55 @@
+package p4p
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Size of the note icon in points.
+const noteIconSize = 20
+
+// A text annotation to add to a page.
+type note struct {
+	page int
+	// Top-left corner of the icon in PDF coordinates.
+	x, y float64
+	text string
+}
+
+// Attaches a sticky note with text to the current page, showing a note icon with its top-left corner
+// at, in points from the top-left corner of the page, that opens the text in a popup.
+func (g *Generator) AddNote(text string, at Coord) error {
+	if g.pdf.PageNo() == 0 {
+		return ErrNoPage
+	}
+	_, h := g.pdf.GetPageSize()
+	g.notes = append(g.notes, note{page: g.pdf.PageNo(), x: at.X, y: h - at.Y, text: text})
+	return nil
+}
+
+// Adds the notes as text annotations to their pages.
+func (g *Generator) putNotes(p *pdfFile) {
+	pages := p.pages()
+	for _, n := range g.notes {
+		i := n.page - p.firstPage
+		if i < 0 || i >= len(pages) {
+			continue
+		}
+		annot := p.add(fmt.Sprintf("<</Type /Annot /Subtype /Text /Rect [%s %s %s %s] /Contents %s /Name /Comment /Open false>>",
+			pdfNumber(n.x), pdfNumber(n.y-noteIconSize), pdfNumber(n.x+noteIconSize), pdfNumber(n.y), pdfString(n.text)))
+		p.addAnnot(pages[i], annot)
+	}
+}
+
+// Adds the annotation object annot to the annotations of page.
+func (p *pdfFile) addAnnot(page, annot int) {
+	ref := fmt.Sprintf("%d 0 R ", annot)
+	obj := p.objs[page]
+	// Pages with links already have an annotations array.
+	if i := bytes.Index(obj, []byte("/Annots [")); i >= 0 {
+		i += len("/Annots [")
+		p.objs[page] = append(append(append([]byte{}, obj[:i]...), ref...), obj[i:]...)
+		return
+	}
+	p.extendDict(page, "/Annots ["+ref+"]")
+}
@@ -0,0 +1,44 @@
+package p4p_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddNote(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddNote("too early", p4p.Coord{}); err != p4p.ErrNoPage {
+		t.Fatal("expected ErrNoPage, got:", err)
+	}
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddNote("Check colors (page 1)", p4p.Coord{X: 100, Y: 50}); err != nil {
+		t.Fatal(err)
+	}
+	// The index page links to the thumbnails, so it already has annotations.
+	if err := g.AppendThumbnailIndex(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddNote("Index", p4p.Coord{X: 10, Y: 10}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+
+	annots := regexp.MustCompile(`/Subtype /Text /Rect \[([\d.]+) ([\d.]+) [\d.]+ ([\d.]+)\] /Contents (\(.*?[^\\]\))`).FindAllSubmatch(pdf, -1)
+	if len(annots) != 2 {
+		t.Fatal("expected 2 text annotations, got:", len(annots))
+	}
+	if string(annots[0][4]) != `(Check colors \(page 1\))` || string(annots[0][1]) != "100" || string(annots[0][3]) != "791.89" {
+		t.Fatalf("unexpected first note: %s", annots[0][0])
+	}
+	if n := len(regexp.MustCompile(`/Annots \[`).FindAll(pdf, -1)); n != 2 {
+		t.Fatal("expected both pages to have a single annotations array, got:", n)
+	}
+	if !bytes.Contains(pdf, []byte("/Subtype /Link")) {
+		t.Fatal("index links are missing")
+	}
+}
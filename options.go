@@ -0,0 +1,46 @@
+package p4p
+
+// Returns a copy of the options with Mode set, for chaining like
+// ImageOptions{}.WithMode(Fit).WithMarginInches(0.5).
+func (opts ImageOptions) WithMode(m Mode) ImageOptions {
+	opts.Mode = m
+	return opts
+}
+
+// Returns a copy of the options with Scale set.
+func (opts ImageOptions) WithScale(f float64) ImageOptions {
+	opts.Scale = f
+	return opts
+}
+
+// Returns a copy of the options with Margins set to f inches on every side, stored in points but
+// marked as such, so that they stay f inches with any unit passed to Render or set with SetUnit.
+func (opts ImageOptions) WithMarginInches(f float64) ImageOptions {
+	m := f * float64(Inch)
+	opts.Margins = Margins{Top: m, Right: m, Bottom: m, Left: m}
+	opts.marginsInPoints = true
+	return opts
+}
+
+// Returns a copy of the options with Margins set to f millimeters on every side, like WithMarginInches.
+func (opts ImageOptions) WithMarginMillimeters(f float64) ImageOptions {
+	return opts.WithMarginInches(f * float64(Millimeter/Inch))
+}
+
+// Returns a copy of the options with Caption set.
+func (opts ImageOptions) WithCaption(caption string) ImageOptions {
+	opts.Caption = caption
+	return opts
+}
+
+// Returns a copy of the options with AltText set.
+func (opts ImageOptions) WithAltText(text string) ImageOptions {
+	opts.AltText = text
+	return opts
+}
+
+// Returns a copy of the options with Rotate set to the given clockwise angle in degrees.
+func (opts ImageOptions) WithRotate(degrees float64) ImageOptions {
+	opts.Rotate = degrees
+	return opts
+}
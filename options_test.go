@@ -0,0 +1,50 @@
+package p4p_test
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestImageOptionsWith(t *testing.T) {
+	base := p4p.ImageOptions{FillBias: p4p.Align{V: p4p.Top}}
+	opts := base.WithMode(p4p.Fill).WithScale(0.8).WithMarginInches(0.5).WithCaption("Gopher").WithRotate(90)
+	if opts.Mode != p4p.Fill || opts.Scale != 0.8 || opts.Caption != "Gopher" || opts.Rotate != 90 {
+		t.Fatal("expected chained values to be set, got:", opts)
+	}
+	if opts.Margins != (p4p.Margins{Top: 36, Right: 36, Bottom: 36, Left: 36}) {
+		t.Fatal("expected half inch margins in points, got:", opts.Margins)
+	}
+	if opts.FillBias != base.FillBias {
+		t.Fatal("expected other fields to be kept")
+	}
+	if base.Mode != p4p.Center || base.Scale != 0 {
+		t.Fatal("expected the original options to be unchanged")
+	}
+	if m := (p4p.ImageOptions{}).WithMarginMillimeters(25.4).Margins.Left; math.Abs(m-72) > 1e-9 {
+		t.Fatal("expected 25.4mm to be 72pt, got:", m)
+	}
+}
+
+func TestMarginInchesWithUnit(t *testing.T) {
+	pg := p4p.A4()
+	opts := p4p.ImageOptions{Mode: p4p.Fit}.WithMarginInches(0.5)
+	want := p4p.RenderLayout(pg, p4p.Point, 100, 100, p4p.ImageOptions{Mode: p4p.Fit, Margins: p4p.Margins{Top: 36, Right: 36, Bottom: 36, Left: 36}})
+	const eps = 1e-6
+	// Half an inch is 12.7mm.
+	if l := p4p.RenderLayout(pg, p4p.Millimeter, 100, 100, opts); math.Abs(l.X*float64(p4p.Millimeter)-want.X) > eps || math.Abs(l.W*float64(p4p.Millimeter)-want.W) > eps {
+		t.Fatalf("expected half inch margins in millimeters, got: %+v", l)
+	}
+
+	g := p4p.NewGenerator(pg)
+	g.SetUnit(p4p.Millimeter)
+	if err := g.AddImage(image.NewGray(image.Rect(0, 0, 100, 100)), opts); err != nil {
+		t.Fatal(err)
+	}
+	d := imageDraws(pageContents(t, writePDF(t, g))[0])[0]
+	if math.Abs(d.X-want.X) > 0.01 || math.Abs(d.W-want.W) > 0.01 {
+		t.Fatalf("expected half inch margins with SetUnit(Millimeter), got: %+v, want: %+v", d, want)
+	}
+}
@@ -0,0 +1,82 @@
+package p4p
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+)
+
+// Returns the EXIF orientation of a JPEG, or of a TIFF image, from 1 (upright) to 8, or 1 if the
+// image has none.
+func exifOrientation(data []byte) int {
+	tiff := data
+	if bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		tiff, _ = exifSegment(data)
+	}
+	order := tiffByteOrder(tiff)
+	if order == nil {
+		return 1
+	}
+	ifd0, err := readIFD(tiff, order, order.Uint32(tiff[4:]))
+	if err != nil {
+		return 1
+	}
+	const orientationTag = 0x0112
+	v := ifd0.value(orientationTag)
+	if len(v) != 2 {
+		return 1
+	}
+	if o := int(order.Uint16(v)); o >= 1 && o <= 8 {
+		return o
+	}
+	return 1
+}
+
+// Decodes an image, turning it upright according to its EXIF orientation.
+func (g *Generator) decodeOriented(data []byte) (image.Image, error) {
+	img, err := g.decodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return orient(img, exifOrientation(data)), nil
+}
+
+// Returns the image transformed from EXIF orientation o to upright.
+func orient(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		// Orientations 5 to 8 swap width and height.
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2: // Mirrored horizontally.
+				dx, dy = w-1-x, y
+			case 3: // Rotated by 180 degrees.
+				dx, dy = w-1-x, h-1-y
+			case 4: // Mirrored vertically.
+				dx, dy = x, h-1-y
+			case 5: // Mirrored along the top-left to bottom-right diagonal.
+				dx, dy = y, x
+			case 6: // Needs turning 90 degrees clockwise.
+				dx, dy = h-1-y, x
+			case 7: // Mirrored along the top-right to bottom-left diagonal.
+				dx, dy = h-1-y, w-1-x
+			case 8: // Needs turning 90 degrees counter-clockwise.
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+4*dx:][:4], src.Pix[y*src.Stride+4*x:])
+		}
+	}
+	return dst
+}
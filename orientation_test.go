@@ -0,0 +1,90 @@
+package p4p_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Returns a JPEG tagged with the given EXIF orientation, 40x20 pixels with a white left half and a black
+// right half as stored.
+func jpegWithOrientation(t *testing.T, orientation uint16) []byte {
+	img := image.NewGray(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			img.SetGray(x, y, color.Gray{Y: 0xff})
+		}
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	be := binary.BigEndian
+	tiff := be.AppendUint32([]byte("MM\x00*"), 8)
+	tiff = be.AppendUint16(tiff, 1)
+	tiff = be.AppendUint16(be.AppendUint16(tiff, 0x0112), 3)
+	tiff = be.AppendUint16(be.AppendUint32(tiff, 1), orientation)
+	tiff = be.AppendUint32(be.AppendUint16(tiff, 0), 0)
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xff, 0xe1}, be.AppendUint16(nil, uint16(len(seg)+2))...)
+	data := b.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(app1, seg...)...), data[2:]...)
+}
+
+func TestExifOrientation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rotated.jpg")
+	if err := os.WriteFile(path, jpegWithOrientation(t, 6), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImageFile(path, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageBytes(jpegWithOrientation(t, 6), p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageReader(bytes.NewReader(jpegWithOrientation(t, 6)), p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// Upright already.
+	if err := g.AddImageBytes(jpegWithOrientation(t, 1), p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	pdf := writePDF(t, g)
+	for i, page := range pageContents(t, pdf) {
+		want := p4p.Rect{W: 20, H: 40}
+		if i == 3 {
+			want = p4p.Rect{W: 40, H: 20}
+		}
+		if d := imageDraws(page)[0]; d.W != want.W || d.H != want.H {
+			t.Fatal("expected page", i+1, "to show the image", want.W, "by", want.H, "got:", d)
+		}
+	}
+
+	paths, err := p4p.ExtractImages(bytes.NewReader(pdf), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The three turned images are identical and share one image object.
+	if len(paths) != 2 {
+		t.Fatal("expected 2 images, got:", len(paths))
+	}
+	img := decodeFile(t, paths[0])
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Fatal("expected the turned image to be 20x40 pixels, got:", b)
+	}
+	// Turned clockwise, the white left half ends up at the top.
+	top, _, _, _ := img.At(10, 5).RGBA()
+	bottom, _, _, _ := img.At(10, 35).RGBA()
+	if top < 0xf000 || bottom > 0x1000 {
+		t.Fatal("expected the turned image to be white at the top and black at the bottom, got:", top, bottom)
+	}
+}
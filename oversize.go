@@ -0,0 +1,58 @@
+package p4p
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+// Returned when adding an image larger than the maximum pixel dimension with RejectOversized.
+var ErrImageTooLarge = errors.New("p4p: image too large")
+
+// Default maximum width and height of embedded images in pixels; some viewers fail to show larger images.
+const DefaultMaxImageDimension = 20000
+
+// What happens to images exceeding the maximum pixel dimension; see SetMaxImageDimension.
+type Oversize int
+
+const (
+	// Downscale oversized images to the maximum dimension before embedding them, laying them out
+	// as if they had their original size.
+	DownscaleOversized Oversize = iota
+	// Fail adding oversized images with ErrImageTooLarge.
+	RejectOversized
+)
+
+// Sets the maximum width and height of embedded images in pixels and what happens to larger images
+// (default: DefaultMaxImageDimension and DownscaleOversized). Zero or less disables the limit.
+func (g *Generator) SetMaxImageDimension(px int, policy Oversize) {
+	g.maxImageDimension = px
+	g.oversize = policy
+}
+
+// Downscales the image to the maximum dimension, and returns options laying it out at its original size.
+// Images within the limit are left unchanged.
+func (g *Generator) limitDimension(p *pendingImage, opts ImageOptions) (ImageOptions, error) {
+	if g.maxImageDimension <= 0 {
+		return opts, nil
+	}
+	size, ok, err := p.size()
+	if err != nil || !ok || max(size.X, size.Y) <= g.maxImageDimension {
+		return opts, err
+	}
+	if g.oversize == RejectOversized {
+		return opts, fmt.Errorf("%w: %dx%d pixels, maximum %d", ErrImageTooLarge, size.X, size.Y, g.maxImageDimension)
+	}
+
+	img, err := g.decodePending(p)
+	if err != nil {
+		return opts, err
+	}
+	scale := float64(g.maxImageDimension) / float64(max(size.X, size.Y))
+	p.replace(resize(img, max(int(math.Round(float64(size.X)*scale)), 1), max(int(math.Round(float64(size.Y)*scale)), 1)))
+	if opts.layoutSize == (image.Point{}) {
+		opts.layoutSize = size
+	}
+	return opts, nil
+}
@@ -0,0 +1,64 @@
+package p4p_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestMaxImageDimension(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 300, 50))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+
+	g := p4p.NewGenerator(p4p.A3())
+	g.SetMaxImageDimension(100, p4p.DownscaleOversized)
+	if err := g.AddImage(img, p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	// Still laid out at the original size.
+	pages := pageContents(t, pdf)
+	if draws := imageDraws(pages[0]); len(draws) != 1 || draws[0].W != 300 || draws[0].H != 50 {
+		t.Fatal("expected the image to be laid out at 300x50, got:", draws)
+	}
+	paths, err := p4p.ExtractImages(bytes.NewReader(pdf), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := decodeFile(t, paths[0]).Bounds().Size(); size != image.Pt(100, 17) {
+		t.Fatal("expected the image to be downscaled to 100x17, got:", size)
+	}
+
+	g = p4p.NewGenerator(p4p.A3())
+	g.SetMaxImageDimension(100, p4p.RejectOversized)
+	if err := g.AddImage(img, p4p.ImageOptions{}); !errors.Is(err, p4p.ErrImageTooLarge) {
+		t.Fatal("expected ErrImageTooLarge, got:", err)
+	}
+	if err := g.AddImage(image.NewGray(image.Rect(0, 0, 100, 100)), p4p.ImageOptions{}); err != nil {
+		t.Fatal("expected an image at the limit to be added, got:", err)
+	}
+}
+
+func TestDefaultMaxImageDimension(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, p4p.DefaultMaxImageDimension+1, 2))
+	for x := 0; x < img.Bounds().Dx(); x++ {
+		img.Set(x, 0, color.NRGBA{0xff, 0, 0, 0x80})
+	}
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := p4p.ExtractImages(bytes.NewReader(writePDF(t, g)), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := decodeFile(t, paths[0]).Bounds().Dx(); w != p4p.DefaultMaxImageDimension {
+		t.Fatal("expected the image to be downscaled to the default maximum, got width:", w)
+	}
+}
@@ -2,16 +2,28 @@ package p4p
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"math"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jung-kurt/gofpdf"
+	"github.com/jung-kurt/gofpdf/contrib/gofpdi"
 )
 
 // Base unit is Pt.
@@ -66,6 +78,34 @@ func Tabloid() PageSize {
 	return PageSize{W: 792, H: 1224, Unit: Point}
 }
 
+// Returns all predefined page sizes, ordered from smallest to largest area.
+func StandardPageSizes() []PageSize {
+	return []PageSize{A6(), A5(), Letter(), A4(), Legal(), Tabloid(), A3(), A2(), A1()}
+}
+
+// Returns the smallest standard page size (in either orientation) that holds an image printed at
+// exactly the given physical width with the given margin on every side, along with the options
+// that place it at that width. Width and margin are in the specified unit. Returns false if no
+// standard page is large enough.
+func PageForPrintWidth(width, margin float64, unit Unit, imgWidthPx, imgHeightPx int) (PageSize, ImageOptions, bool) {
+	opts := ImageOptions{
+		Mode: Center,
+		// Center maps one pixel to one point before scaling.
+		Scale: width * float64(unit) / float64(imgWidthPx),
+	}
+	w := width + 2*margin
+	h := width*float64(imgHeightPx)/float64(imgWidthPx) + 2*margin
+	for _, s := range StandardPageSizes() {
+		for _, s := range []PageSize{s, s.Rotate()} {
+			sz := s.Convert(unit)
+			if w <= sz.W && h <= sz.H {
+				return s, opts, true
+			}
+		}
+	}
+	return PageSize{}, ImageOptions{}, false
+}
+
 // Rotates the page size by 90 degrees (switching to landscape on default page sizes).
 func (s PageSize) Rotate() PageSize {
 	return PageSize{W: s.H, H: s.W, Unit: s.Unit}
@@ -84,7 +124,7 @@ func (s PageSize) Convert(to Unit) PageSize {
 type Mode int
 
 const (
-	// Center image on page; default DPI: 72.
+	// Center image on page at its size at ImageOptions.DPI (default: 72).
 	Center Mode = iota
 	// Scale image to the maximum size where it remains entirely visible.
 	Fit
@@ -92,20 +132,335 @@ const (
 	Fill
 )
 
+type HAlign int
+
+const (
+	CenterH HAlign = iota
+	Left
+	Right
+)
+
+type VAlign int
+
+const (
+	CenterV VAlign = iota
+	Top
+	Bottom
+)
+
+// Horizontal and vertical alignment; the zero value centers on both axes.
+type Align struct {
+	H HAlign
+	V VAlign
+}
+
+// Returns the position of an object aligned within the given free space (which may be negative
+// if the object is larger than its container).
+func (a Align) offset(freeW, freeH float64) (x, y float64) {
+	switch a.H {
+	case CenterH:
+		x = freeW / 2
+	case Right:
+		x = freeW
+	}
+	switch a.V {
+	case CenterV:
+		y = freeH / 2
+	case Bottom:
+		y = freeH
+	}
+	return
+}
+
 type ImageOptions struct {
 	Mode Mode
 	// Scale the image's size before positioning; works with all layouts (default: 1).
 	Scale float64
+	// Resolution the image's size is derived from with Center and NoUpscale, e.g. 300 to place a 3000 pixels
+	// wide image 10 inches wide (default: 72).
+	DPI float64
+	// Width divided by height of images whose width or height is unknown, i.e. passed to Render as 0
+	// (default: 1). Images added to a Generator always use their own.
+	AspectRatio float64
+	// Position of the image within the page with Center and Fit, e.g. Left and Top to pin it to the top-left
+	// corner (default: centered).
+	Align Align
+	// Moves the image by the given amount after it has been positioned, in the same units as the image's
+	// placement, e.g. to match pre-printed stationery: positive OffsetX moves it right, positive OffsetY down.
+	// Parts moved out of the area within the margins are cropped.
+	OffsetX, OffsetY float64
+	// Which side of the image Fill keeps when cropping, e.g. Top only crops off the bottom (default: centered).
+	FillBias Align
+	// Point of the image that Fill keeps as close to the center as cropping allows, e.g. a face, as fractions
+	// of the image's width and height from its top-left corner; overrides FillBias.
+	FocalPoint *Coord
+	// Limits how far Fill may zoom in beyond Fit, e.g. 1.2 for 20%, cropping as little as needed and
+	// letterboxing the rest when that's not enough to fill the rect (default: unlimited). Values below 1
+	// are treated as 1.
+	MaxFillZoom float64
+	// Never crop the image: Fill behaves like Fit, images too large for the page in Center mode or with Scale
+	// are shrunk to fit it, and CropMargins is ignored. NeedsCrop is false unless OffsetX or OffsetY move the
+	// image out of the area within the margins.
+	NoCrop bool
+	// Never enlarge images beyond their size in Center mode (see DPI) to fit or fill the page, centering
+	// smaller images instead; Scale still applies on top.
+	NoUpscale bool
+	// Alternate text describing the image; tags the image as a figure for accessibility.
+	AltText string
+	// Text printed centered below the image; the image is shrunk to make room for it. Images spread across
+	// several slices with FlowHorizontal, Columns or SplitTall are captioned below their last slice and get
+	// their alternate text on the first.
+	Caption string
+	// Scale bar drawn over the image, for images of known physical scale such as micrographs.
+	ScaleBar ScaleBarOptions
+	// Edges of the page the printer cannot print on; images are laid out and clipped within the rest of the page.
+	PrinterMargins Margins
+	// Border left empty around the image, within PrinterMargins; images are laid out and clipped within the rest.
+	Margins Margins
+	// Extra margin on the binding edge of images with a page of their own in Center or Fit mode, in the
+	// same units as Margins, shifting them away from the binding.
+	Gutter float64
+	// Edge of the page the binding and Gutter are on (default: GutterLeft).
+	GutterSide GutterSide
+	// Trims the border of uniform color around the image, such as the margins of a scanned page, before
+	// laying it out; with Fit, the content is fitted to the page leaving Margins around it.
+	TrimBorder bool
+	// Trims the given amount off each side of the image after it has been laid out, without moving the rest of the image.
+	CropMargins Margins
+	// Scale the image to the page height and spread it across as many pages as needed, each showing the next
+	// horizontal slice of the image; Mode and Scale are ignored.
+	FlowHorizontal bool
+	// Scale the image to the width of a column and lay it out top to bottom in that many side-by-side columns,
+	// like newspaper text, continuing on new pages as needed; Mode and Scale are ignored. Values below 2 and
+	// FlowHorizontal disable columns.
+	Columns int
+	// Scale the image to the page width and lay it out top to bottom across as many pages as needed, like
+	// Columns with a single column, but end each page at a row of uniform color near its bottom, e.g. between
+	// two lines of a long screenshot of text, rather than cutting through them. With Columns, columns are split
+	// the same way. Mode and Scale are ignored; FlowHorizontal disables it.
+	SplitTall bool
+	// With AddImageFile, size the image's pages by the words in its file name: a standard page size such as
+	// A5 or Letter, landscape or portrait, or both, e.g. "scan_A5_landscape.png". Other words are ignored.
+	FilenameHints bool
+	// Fill the page behind the image with a gray checkerboard, making transparent parts of the image visible.
+	CheckerboardBackground bool
+	// Fill the page area behind the image with a solid color, overriding the generator's page background.
+	BackgroundColor color.Color
+	// With Fit, fill the bars beside the image by mirroring the image across its edges instead of
+	// leaving them empty. Ignored with CropMargins or Rotate.
+	EdgeExtend bool
+	// Rotate the placed image clockwise by the given number of degrees. Images rotated around their center
+	// are sized and aligned by their bounding box, e.g. Fit fits a landscape image rotated by 90 degrees to a
+	// portrait page; rotated Fill images are always centered.
+	Rotate float64
+	// Pivot of the rotation relative to the image's top-left corner, in the same units as the image's
+	// placement (default: image center).
+	RotateOrigin *Coord
+	// Color washed over the image, e.g. for themed sections.
+	Tint color.Color
+	// Opacity of Tint from 0 to 1 (default: 0.5).
+	TintOpacity float64
+	// Darkens the image towards its corners with the given strength from 0 to 1, e.g. 0.3 for a subtle vignette.
+	Vignette float64
+	// Sets the /Interpolate flag of the embedded image, asking viewers to smooth (true) or not to smooth
+	// (false) it when scaling, which otherwise is up to the viewer.
+	Interpolate *bool
+	// Optional content layer the image, its background and caption are drawn on (default: none).
+	Layer LayerID
+	// Embed images added from memory as JPEG without scanning every pixel for transparency, which is
+	// slow for large images known to be opaque.
+	AssumeOpaque bool
+	// Embed images added from memory as PNG, keeping them lossless and with any alpha channel, even if
+	// they turn out to be opaque; overrides AssumeOpaque.
+	AssumeAlpha bool
+	// Quality from 1 to 100 of images added from memory that are embedded as JPEG (default: 75).
+	JPEGQuality int
+	// Color the transparent parts of images added from memory are flattened onto when they are embedded
+	// as JPEG, e.g. with AssumeOpaque (default: BackgroundColor, or white without one).
+	FlattenColor color.Color
+
+	// Pixel dimensions used for the layout instead of the registered image's, if set.
+	layoutSize image.Point
+	// File path or URL the image was read from, listed by WriteIndex.
+	source string
+	// Part of the image laid out with layoutSize that the registered image consists of, if it was
+	// cropped before embedding it.
+	pixelCrop image.Rectangle
+	// Image added from memory, encoded only once it has been cropped.
+	decoded image.Image
+	// Whether lengths have been converted from the generator's unit to points.
+	inPoints bool
+	// Whether Margins are in points regardless of the unit, as set by WithMarginInches.
+	marginsInPoints bool
 }
 
-// Returns an the image layout if rendered onto a the specified page in specified units.
-// Cropping coordinates are in pixels on the image. Cropping is only necessary if crop returns true.
-func Render(pageSize PageSize, unit Unit, imgWidthPx, imgHeightPx int, opts ImageOptions) (x, y, w, h float64, cropX1, cropY1, cropX2, cropY2 int, crop bool) {
+// A position on a page or image.
+type Coord struct {
+	X, Y float64
+}
+
+// Returns the point the image laid out at x, y, w, h is rotated around.
+func (opts ImageOptions) pivot(x, y, w, h float64) (float64, float64) {
+	if o := opts.RotateOrigin; o != nil {
+		return x + o.X, y + o.Y
+	}
+	return x + w/2, y + h/2
+}
+
+// Returns the bounding box of an image laid out at x, y, w, h after applying the rotation in opts.
+func RotatedBounds(x, y, w, h float64, opts ImageOptions) Rect {
+	px, py := opts.pivot(x, y, w, h)
+	return rotatedBounds(Rect{X: x, Y: y, W: w, H: h}, px, py, opts.Rotate)
+}
+
+// Returns the bounding box of r rotated clockwise by deg degrees around px, py.
+func rotatedBounds(r Rect, px, py, deg float64) Rect {
+	sin, cos := math.Sincos(deg * math.Pi / 180)
+	x1, y1 := math.Inf(1), math.Inf(1)
+	x2, y2 := math.Inf(-1), math.Inf(-1)
+	for _, c := range []Coord{{r.X, r.Y}, {r.X + r.W, r.Y}, {r.X, r.Y + r.H}, {r.X + r.W, r.Y + r.H}} {
+		// Clockwise, since y points down.
+		dx, dy := c.X-px, c.Y-py
+		rx, ry := px+dx*cos-dy*sin, py+dx*sin+dy*cos
+		x1, y1 = min(x1, rx), min(y1, ry)
+		x2, y2 = max(x2, rx), max(y2, ry)
+	}
+	return Rect{X: x1, Y: y1, W: x2 - x1, H: y2 - y1}
+}
+
+// Space on each side of a page, in the unit passed to Render (points when adding images to a Generator).
+type Margins struct {
+	Top, Right, Bottom, Left float64
+}
+
+// Returns the part of rect that images are laid out in.
+func (opts ImageOptions) contentRect(rect Rect) Rect {
+	return rect.inset(opts.margins())
+}
+
+// Returns the margins multiplied by k, e.g. to convert them to a different unit.
+func (m Margins) scale(k float64) Margins {
+	return Margins{Top: m.Top * k, Right: m.Right * k, Bottom: m.Bottom * k, Left: m.Left * k}
+}
+
+// Returns the space around the image taken up by PrinterMargins and Margins together.
+func (opts ImageOptions) margins() Margins {
+	p, m := opts.PrinterMargins, opts.Margins
+	return Margins{Top: p.Top + m.Top, Right: p.Right + m.Right, Bottom: p.Bottom + m.Bottom, Left: p.Left + m.Left}
+}
+
+// Returns the visible part of an image laid out at x, y, w, h within rect.
+func (opts ImageOptions) clipRect(rect Rect, x, y, w, h float64) Rect {
+	img := Rect{X: x, Y: y, W: w, H: h}.inset(opts.CropMargins)
+	if opts.Rotate != 0 {
+		px, py := opts.pivot(x, y, w, h)
+		img = rotatedBounds(img, px, py, opts.Rotate)
+	}
+	return opts.contentRect(rect).intersect(img)
+}
+
+// A rectangle on a page, positioned by its top-left corner.
+type Rect struct {
+	X, Y float64
+	W, H float64
+}
+
+// Returns the rectangle shrunk by the margins.
+func (r Rect) inset(m Margins) Rect {
+	return Rect{
+		X: r.X + m.Left,
+		Y: r.Y + m.Top,
+		W: r.W - m.Left - m.Right,
+		H: r.H - m.Top - m.Bottom,
+	}
+}
+
+// Returns the overlapping area of both rectangles.
+func (r Rect) intersect(o Rect) Rect {
+	x1, y1 := max(r.X, o.X), max(r.Y, o.Y)
+	x2, y2 := min(r.X+r.W, o.X+o.W), min(r.Y+r.H, o.Y+o.H)
+	return Rect{X: x1, Y: y1, W: max(x2-x1, 0), H: max(y2-y1, 0)}
+}
+
+// Placement of an image on a page, as returned by RenderLayout.
+type Layout struct {
+	// Position and size of the whole image, which may extend beyond the page.
+	X, Y, W, H float64
+	// Part of the image that is visible, in pixels on the image; the whole image unless NeedsCrop.
+	Crop image.Rectangle
+	// Whether the image must be cropped to Crop.
+	NeedsCrop bool
+}
+
+// Returns the image layout if rendered onto the specified page in specified units.
+// If the width or height is not known yet and passed as 0, it follows from the other one and
+// opts.AspectRatio; without either dimension, the image is laid out empty.
+func RenderLayout(pageSize PageSize, unit Unit, imgWidthPx, imgHeightPx int, opts ImageOptions) Layout {
 	pgSz := pageSize.Convert(unit)
-	pgW, pgH := pgSz.W, pgSz.H
+	return RenderLayoutInRect(Rect{W: pgSz.W, H: pgSz.H}, unit, imgWidthPx, imgHeightPx, opts)
+}
+
+// Same as RenderLayout, but returns the layout as separate values. Cropping coordinates are in pixels
+// on the image. Cropping is only necessary if crop returns true.
+func Render(pageSize PageSize, unit Unit, imgWidthPx, imgHeightPx int, opts ImageOptions) (x, y, w, h float64, cropX1, cropY1, cropX2, cropY2 int, crop bool) {
+	return RenderLayout(pageSize, unit, imgWidthPx, imgHeightPx, opts).values()
+}
+
+// Same as RenderLayoutInRect, but returns the layout as separate values like Render.
+func RenderInRect(rect Rect, unit Unit, imgWidthPx, imgHeightPx int, opts ImageOptions) (x, y, w, h float64, cropX1, cropY1, cropX2, cropY2 int, crop bool) {
+	return RenderLayoutInRect(rect, unit, imgWidthPx, imgHeightPx, opts).values()
+}
+
+func (l Layout) values() (x, y, w, h float64, cropX1, cropY1, cropX2, cropY2 int, crop bool) {
+	return l.X, l.Y, l.W, l.H, l.Crop.Min.X, l.Crop.Min.Y, l.Crop.Max.X, l.Crop.Max.Y, l.NeedsCrop
+}
+
+// Same as RenderLayout, but lays the image out within the given rectangle (in specified units) instead of the
+// whole page. Returned coordinates are relative to the page; the crop crops the image to the rectangle.
+func RenderLayoutInRect(rect Rect, unit Unit, imgWidthPx, imgHeightPx int, opts ImageOptions) Layout {
+	if opts.marginsInPoints && unit > 0 {
+		opts.Margins = opts.Margins.scale(1 / float64(unit))
+		opts.marginsInPoints = false
+	}
+	if imgWidthPx <= 0 && imgHeightPx <= 0 {
+		c := opts.contentRect(rect)
+		return Layout{X: c.X, Y: c.Y}
+	}
+	ratio := opts.AspectRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	if imgWidthPx <= 0 {
+		imgWidthPx = max(int(math.Round(float64(imgHeightPx)*ratio)), 1)
+	} else if imgHeightPx <= 0 {
+		imgHeightPx = max(int(math.Round(float64(imgWidthPx)/ratio)), 1)
+	}
+	var x, y, w, h float64
+	var cropX1, cropY1, cropX2, cropY2 int
+	var crop bool
+	if opts.NoCrop {
+		if opts.Mode == Fill {
+			opts.Mode = Fit
+		}
+		opts.CropMargins = Margins{}
+	}
+	rect = opts.contentRect(rect)
+	pgW, pgH := rect.W, rect.H
 
 	imgW := float64(imgWidthPx) / float64(unit)
 	imgH := float64(imgHeightPx) / float64(unit)
+	if opts.DPI > 0 {
+		imgW = float64(imgWidthPx) / opts.DPI * float64(Inch) / float64(unit)
+		imgH = float64(imgHeightPx) / opts.DPI * float64(Inch) / float64(unit)
+	}
+
+	// Images rotated around their center are laid out by their bounding box.
+	rotated := opts.Rotate != 0 && opts.RotateOrigin == nil
+	var sin, cos float64
+	if rotated {
+		sin, cos = math.Sincos(opts.Rotate * math.Pi / 180)
+		sin, cos = math.Abs(sin), math.Abs(cos)
+	}
 
 	// Calculate coords.
 	{
@@ -113,17 +468,37 @@ func Render(pageSize PageSize, unit Unit, imgWidthPx, imgHeightPx int, opts Imag
 		case Center:
 			w, h = imgW, imgH
 		case Fit:
-			if imgW/imgH > pgW/pgH {
+			if rotated {
+				k := min(pgW/(imgW*cos+imgH*sin), pgH/(imgW*sin+imgH*cos))
+				w, h = imgW*k, imgH*k
+			} else if imgW/imgH > pgW/pgH {
 				w, h = pgW, pgW*imgH/imgW
 			} else {
 				w, h = pgH*imgW/imgH, pgH
 			}
 		case Fill:
-			if imgW/imgH < pgW/pgH {
+			if rotated {
+				// Large enough to cover the page rotated the other way.
+				k := max((pgW*cos+pgH*sin)/imgW, (pgW*sin+pgH*cos)/imgH)
+				if opts.MaxFillZoom > 0 {
+					k = min(k, min(pgW/(imgW*cos+imgH*sin), pgH/(imgW*sin+imgH*cos))*max(opts.MaxFillZoom, 1))
+				}
+				w, h = imgW*k, imgH*k
+			} else if imgW/imgH < pgW/pgH {
 				w, h = pgW, pgW*imgH/imgW
 			} else {
 				w, h = pgH*imgW/imgH, pgH
 			}
+			if opts.MaxFillZoom > 0 && !rotated {
+				// Fit scales the image by the smaller factor of the two.
+				if maxW := min(pgW, pgH*imgW/imgH) * max(opts.MaxFillZoom, 1); w > maxW {
+					w, h = maxW, maxW*imgH/imgW
+				}
+			}
+		}
+
+		if opts.NoUpscale && opts.Mode != Center && w > imgW {
+			w, h = imgW, imgH
 		}
 
 		if opts.Scale > 0 {
@@ -131,10 +506,36 @@ func Render(pageSize PageSize, unit Unit, imgWidthPx, imgHeightPx int, opts Imag
 			h *= opts.Scale
 		}
 
-		switch opts.Mode {
-		case Center, Fit, Fill:
-			x, y = pgW/2-w/2, pgH/2-h/2
+		if opts.NoCrop {
+			extW, extH := w, h
+			if rotated {
+				extW, extH = w*cos+h*sin, w*sin+h*cos
+			}
+			if k := min(pgW/extW, pgH/extH); k < 1 {
+				w, h = w*k, h*k
+			}
 		}
+
+		bw, bh := w*cos+h*sin, w*sin+h*cos
+		switch {
+		case rotated && opts.Mode == Fill:
+			x, y = (pgW-w)/2, (pgH-h)/2
+		case rotated:
+			bx, by := opts.Align.offset(pgW-bw, pgH-bh)
+			x, y = bx+(bw-w)/2, by+(bh-h)/2
+		case opts.Mode == Center, opts.Mode == Fit:
+			x, y = opts.Align.offset(pgW-w, pgH-h)
+		case opts.Mode == Fill:
+			if f := opts.FocalPoint; f != nil {
+				// Centered on the focal point, but never past the image's edges.
+				x = min(max(pgW/2-f.X*w, min(pgW-w, 0)), max(pgW-w, 0))
+				y = min(max(pgH/2-f.Y*h, min(pgH-h, 0)), max(pgH-h, 0))
+			} else {
+				x, y = opts.FillBias.offset(pgW-w, pgH-h)
+			}
+		}
+		x += opts.OffsetX
+		y += opts.OffsetY
 	}
 
 	// Calculate cropping coords.
@@ -142,10 +543,10 @@ func Render(pageSize PageSize, unit Unit, imgWidthPx, imgHeightPx int, opts Imag
 		// Size of an image pixel in units
 		pxW := w / float64(imgWidthPx)
 		pxH := h / float64(imgHeightPx)
-		// Image coords on page in image pixels
+		// Image coords in rect in image pixels
 		imgX1, imgY1 := x/pxW, y/pxH
 		imgX2, imgY2 := imgX1+float64(imgWidthPx), imgY1+float64(imgHeightPx)
-		// Page size in image pixels
+		// Rect size in image pixels
 		pgWPx, pgHPx := pgW/pxW, pgH/pxH
 
 		cropX1, cropY1, cropX2, cropY2 = 0, 0, imgWidthPx, imgHeightPx
@@ -158,97 +559,1132 @@ func Render(pageSize PageSize, unit Unit, imgWidthPx, imgHeightPx int, opts Imag
 			cropY1 = int(-imgY1)
 			crop = true
 		}
-		if imgX2 > pgWPx+imgX1 {
+		if imgX2 > pgWPx {
 			cropX2 = int(pgWPx - imgX1)
 			crop = true
 		}
-		if imgY2 > pgHPx+imgY1 {
+		if imgY2 > pgHPx {
 			cropY2 = int(pgHPx - imgY1)
 			crop = true
 		}
+
+		if m := opts.CropMargins; m != (Margins{}) {
+			cropX1 = max(cropX1, int(m.Left/pxW))
+			cropY1 = max(cropY1, int(m.Top/pxH))
+			cropX2 = min(cropX2, imgWidthPx-int(m.Right/pxW))
+			cropY2 = min(cropY2, imgHeightPx-int(m.Bottom/pxH))
+			crop = true
+		}
+		if rotated && opts.CropMargins == (Margins{}) {
+			// Whether the bounding box sticks out, give or take rounding errors.
+			const eps = 1e-9
+			bw, bh := w*cos+h*sin, w*sin+h*cos
+			bx, by := x+(w-bw)/2, y+(h-bh)/2
+			crop = bx < -eps || by < -eps || bx+bw > pgW+eps || by+bh > pgH+eps
+		}
+		if opts.NoCrop && opts.OffsetX == 0 && opts.OffsetY == 0 {
+			// Whatever sticks out is down to rounding errors.
+			cropX1, cropY1, cropX2, cropY2, crop = 0, 0, imgWidthPx, imgHeightPx, false
+		}
 	}
 
-	return
+	return Layout{
+		X:         x + rect.X,
+		Y:         y + rect.Y,
+		W:         w,
+		H:         h,
+		Crop:      image.Rectangle{Min: image.Pt(cropX1, cropY1), Max: image.Pt(cropX2, cropY2)},
+		NeedsCrop: crop,
+	}
 }
 
 type Generator struct {
+	// Serializes concurrent calls adding images.
+	mu         sync.Mutex
 	pdf        *gofpdf.Fpdf
 	imageIndex int
 	pageSize   PageSize
+	// Sum of the encoded sizes of all added images.
+	imageBytes int64
+	httpClient *http.Client
+	lang       string
+	figures    []figure
+	// Caption numbering.
+	captionFormat string
+	figureCount   int
+	// Dates set with SetDates.
+	created, modified time.Time
+	// Converts UTF-8 to the encoding of the core fonts.
+	tr func(string) string
+	// Images repeated on every page.
+	header, footer *band
+	// Prepended to the names images are registered under in gofpdf.
+	imageNamePrefix     string
+	colorProfileWarning func(source string, profile *ICCProfile)
+	pageProgression     string
+	startOnRight        bool
+	objectStreams       bool
+	tumbleDuplex        bool
+	nav                 *NavOptions
+	pageBackground      color.Color
+	// Number of the blank page inserted with SetStartOnRight, or 0.
+	blankPage int
+	// /Interpolate flags of images, by gofpdf image ID.
+	interpolate map[string]bool
+	// CMYK images replacing their placeholders, by gofpdf image ID.
+	cmyk map[string]cmykImage
+	// Optional content layers of images added with a preview.
+	previewLayer, fullResLayer LayerID
+	// EXIF GPS locations of images, by page number.
+	geoLocations map[int]GeoLocation
+	// Sticky notes added with AddNote.
+	notes         []note
+	decodeTimeout time.Duration
+	progress      func(Progress)
+	// Duplicate detection in AddImageFiles.
+	dedup             Dedup
+	seenFiles         map[[sha256.Size]byte]bool
+	seenHashes        []uint64
+	maxImageDimension int
+	oversize          Oversize
+	// Whether the document is fingerprinted, and the SHA-256 hashes of its images so far.
+	fingerprint bool
+	imageHashes [][]byte
+	// Passwords and permissions of encrypted documents, see SetProtection.
+	protection *protection
+	// Imports pages of vector PDFs, created on first use.
+	importer *gofpdi.Importer
+	// Placements of all images, written by WriteIndex.
+	index []IndexEntry
+	// Whether pages of single images are sized by the images' DPI, and how these sizes are rounded.
+	pageSizeFromDPI  bool
+	pageSizeSnapping PageSizeSnapping
+	// Resolution pages of single images are sized to A-series sizes at, if any, and whether these
+	// pages are turned to the images' orientation.
+	aSeriesDPI float64
+	autoOrient bool
+	// Space between the cells of AddGrid, and the layout of its last page.
+	gridGutter     float64
+	lastPageLayout LastPageLayout
+	// Page numbering of pages added from now on, if any, and pages to number when writing.
+	pageNumbers   *PageNumberOptions
+	numberedPages []numberedPage
+	mirrorOutput  bool
+	monochrome    bool
+	// Font of captions, see SetCaptionFont, and whether files are captioned with their names.
+	captionFont      string
+	captionSize      float64
+	filenameCaptions bool
+	// Resolution cap and encoding of images, see SetQuality.
+	maxDPI      float64
+	jpegQuality int
+	lossless    bool
+	// Options of the image on page one, if any.
+	cover *ImageOptions
+	// Unit of lengths in ImageOptions; 0 means points.
+	unit Unit
+	// Placement resolution below which images get a badge, and its appearance.
+	lowResDPI   float64
+	lowResBadge BadgeOptions
 }
 
 func NewGenerator(pageSize PageSize) *Generator {
+	g := &Generator{}
+	g.init(pageSize)
+	return g
+}
+
+// Discards all pages and settings, leaving the generator as NewGenerator created it with its original page
+// size, so that it can build a new document. Must not be called concurrently with other methods.
+func (g *Generator) Reset() {
+	g.init(g.pageSize)
+}
+
+// Sets up the generator for an empty document.
+func (g *Generator) init(pageSize PageSize) {
 	pageSizePt := pageSize.Convert(Point)
-	return &Generator{
-		pdf: gofpdf.NewCustom(&gofpdf.InitType{
-			OrientationStr: "P",
-			UnitStr:        "pt",
-			Size:           gofpdf.SizeType{Wd: pageSizePt.W, Ht: pageSizePt.H},
-		}),
-		pageSize: pageSizePt,
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "pt",
+		Size:           gofpdf.SizeType{Wd: pageSizePt.W, Ht: pageSizePt.H},
+	})
+	// Everything is placed explicitly, text must never spill onto a new page.
+	pdf.SetAutoPageBreak(false, 0)
+	*g = Generator{
+		pdf:               pdf,
+		pageSize:          pageSizePt,
+		imageNamePrefix:   "p4p_",
+		maxImageDimension: DefaultMaxImageDimension,
+	}
+}
+
+// Sets the prefix of the names images are registered under (default: "p4p_"), to avoid
+// collisions with images registered by other code working on the same document.
+func (g *Generator) SetImageNamePrefix(prefix string) {
+	g.imageNamePrefix = prefix
+}
+
+// Sets the unit of the lengths in ImageOptions, i.e. margins, offsets and RotateOrigin (default: Point), for
+// images added afterwards. Rects passed to the generator's methods are in points regardless.
+func (g *Generator) SetUnit(u Unit) {
+	g.unit = u
+}
+
+// Returns the options with their lengths converted from the generator's unit to points.
+func (g *Generator) optsInPoints(opts ImageOptions) ImageOptions {
+	if opts.inPoints {
+		return opts
+	}
+	opts.inPoints = true
+	if g.unit == 0 || g.unit == Point {
+		return opts
+	}
+	k := float64(g.unit)
+	opts.PrinterMargins = opts.PrinterMargins.scale(k)
+	if !opts.marginsInPoints {
+		opts.Margins = opts.Margins.scale(k)
+	}
+	opts.marginsInPoints = false
+	opts.Gutter *= k
+	opts.CropMargins = opts.CropMargins.scale(k)
+	if o := opts.RotateOrigin; o != nil {
+		opts.RotateOrigin = &Coord{X: o.X * k, Y: o.Y * k}
+	}
+	opts.OffsetX *= k
+	opts.OffsetY *= k
+	return opts
+}
+
+// Sizes the page of each image added afterwards to the image's physical size at the resolution stored
+// in its JPEG (JFIF) or PNG (pHYs) metadata, e.g. 8.5x11in for a 2550x3300 pixel scan at 300 DPI.
+// Images without resolution metadata get the generator's page size.
+func (g *Generator) SetPageSizeFromDPI(enabled bool) {
+	g.pageSizeFromDPI = enabled
+}
+
+// Fills every page added afterwards with a solid color behind its content; ImageOptions.BackgroundColor
+// overrides it behind individual images. Passing nil removes the background.
+func (g *Generator) SetPageBackgroundColor(c color.Color) {
+	g.pageBackground = c
+}
+
+// Registers the image and draws it within the given rectangle (in points) on the current page.
+func (g *Generator) placeImage(typ string, r io.Reader, rect Rect, opts ImageOptions) error {
+	img, err := g.registerImage(typ, r, rect, opts)
+	if err != nil {
+		return err
+	}
+	g.drawImage(img)
+	return nil
+}
+
+// An image registered with gofpdf, ready to be drawn.
+type registeredImage struct {
+	name string
+	opt  gofpdf.ImageOptions
+	info *gofpdf.ImageInfoType
+	// Rectangle the image is placed within, and the part of it left to the image by the caption.
+	rect, area Rect
+	caption    string
+	opts       ImageOptions
+}
+
+// An image on its way to being registered, read and decoded only once a step of the registration needs it,
+// and encoded again only if one has changed it.
+type pendingImage struct {
+	typ string
+	// The encoded image, until it has been read into data.
+	r    io.Reader
+	data []byte
+	read bool
+	// The decoded image, if any, and whether it differs from the encoded one.
+	img     image.Image
+	changed bool
+}
+
+// Returns the encoded image, reading it if it hasn't been read yet.
+func (p *pendingImage) bytes() ([]byte, error) {
+	if !p.read {
+		data, err := io.ReadAll(p.r)
+		if err != nil {
+			return nil, err
+		}
+		p.data, p.read, p.r = data, true, nil
+	}
+	return p.data, nil
+}
+
+// Returns the pixel size of the image, or false if it cannot be decoded, which is left for gofpdf to report.
+func (p *pendingImage) size() (image.Point, bool, error) {
+	if p.img != nil {
+		return p.img.Bounds().Size(), true, nil
+	}
+	data, err := p.bytes()
+	if err != nil {
+		return image.Point{}, false, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return image.Point{}, false, nil
+	}
+	return image.Pt(cfg.Width, cfg.Height), true, nil
+}
+
+// Returns the decoded image, decoding it if it hasn't been decoded yet.
+func (g *Generator) decodePending(p *pendingImage) (image.Image, error) {
+	if p.img == nil {
+		data, err := p.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if p.typ == cmykType {
+			p.img, err = decodeCMYK(bytes.NewReader(data))
+		} else {
+			p.img, err = g.decodeImage(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.img, nil
+}
+
+// Replaces the image with a changed version of it.
+func (p *pendingImage) replace(img image.Image) {
+	p.img, p.changed = img, true
+}
+
+// Returns the image ready for gofpdf: as it was encoded, unless it has been changed.
+func (p *pendingImage) encoded(opts ImageOptions) (string, io.Reader, error) {
+	if p.changed {
+		return encodeImage(p.img, opts)
 	}
+	if p.read {
+		return p.typ, bytes.NewReader(p.data), nil
+	}
+	return p.typ, p.r, nil
 }
 
-func (g *Generator) addImage(typ string, r io.Reader, opts ImageOptions) {
-	name := "p4p_image_" + strconv.Itoa(g.imageIndex)
+// Registers the image to be drawn within the given rectangle (in points), without touching any page.
+// A failed registration leaves the document as it was.
+func (g *Generator) registerImage(typ string, r io.Reader, rect Rect, opts ImageOptions) (*registeredImage, error) {
+	if err := g.pdf.Error(); err != nil {
+		return nil, err
+	}
+	opts = g.withQuality(g.optsInPoints(opts))
+	name := g.imageNamePrefix + "image_" + strconv.Itoa(g.imageIndex)
 	g.imageIndex++
-	g.pdf.AddPage()
 
+	area := rect
+	if opts.Caption != "" {
+		area.H -= g.captionHeight()
+	}
+
+	// Every step changes the decoded image, which is encoded once at the end.
+	p := &pendingImage{typ: typ, r: r}
+	if opts.decoded != nil {
+		p.replace(opts.decoded)
+		opts.decoded = nil
+	}
+	var err error
+	if opts.cropsPixels() {
+		if opts, err = g.cropToLayout(p, area, opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts, err = g.limitDimension(p, opts); err != nil {
+		return nil, err
+	}
+	if opts, err = g.limitDPI(p, area, opts); err != nil {
+		return nil, err
+	}
+	if err = g.toMonochrome(p); err != nil {
+		return nil, err
+	}
+	cmyk, err := g.cmykPending(p)
+	if err != nil {
+		return nil, err
+	}
+	if typ, r, err = p.encoded(opts); err != nil {
+		return nil, err
+	}
 	opt := gofpdf.ImageOptions{
-		ImageType:             typ,
+		ImageType:             gofpdfType(typ),
 		AllowNegativePosition: true,
 	}
 
+	r, sum := g.hashImage(r)
+	if t := strings.ToLower(typ); t == "jpg" || t == "jpeg" {
+		var err error
+		if r, err = withPlacementDPI(r, area, opts); err != nil {
+			return nil, err
+		}
+	}
+	cr := &countingReader{r: r}
 	info := g.pdf.RegisterImageOptionsReader(
 		name,
 		opt,
-		r,
+		cr,
 	)
+	if err := g.pdf.Error(); err != nil {
+		// Nothing has been drawn, the document is fine without the image.
+		g.pdf.ClearError()
+		return nil, err
+	}
+	g.imageBytes += cr.n
+	if sum != nil {
+		g.imageHashes = append(g.imageHashes, sum.Sum(nil))
+	}
+	if opts.Interpolate != nil {
+		if err := g.setInterpolate(info, *opts.Interpolate); err != nil {
+			return nil, err
+		}
+	}
+	if cmyk != nil {
+		if err := g.setCMYK(info, cmyk); err != nil {
+			return nil, err
+		}
+	}
+	// Numbered only now, so that images failing to register don't use up figure numbers.
+	caption := g.captionText(opts.Caption)
+	return &registeredImage{name: name, opt: opt, info: info, rect: rect, area: area, caption: caption, opts: opts}, nil
+}
 
-	x, y, w, h, _, _, _, _, _ := Render(g.pageSize, Point, int(info.Width()), int(info.Height()), opts)
+// Draws a registered image on the current page.
+func (g *Generator) drawImage(img *registeredImage) {
+	name, opt, info, rect, area, caption, opts := img.name, img.opt, img.info, img.rect, img.area, img.caption, img.opts
 
-	g.pdf.ImageOptions(name, x, y, w, h, false, opt, 0, "")
+	imgW, imgH := int(info.Width()), int(info.Height())
+	if opts.layoutSize != (image.Point{}) {
+		imgW, imgH = opts.layoutSize.X, opts.layoutSize.Y
+	}
+	l := RenderLayoutInRect(area, Point, imgW, imgH, opts)
+	x, y, w, h, crop := l.X, l.Y, l.W, l.H, l.NeedsCrop
+	entry := IndexEntry{Page: g.pdf.PageNo(), Source: opts.source, Rect: Rect{X: x, Y: y, W: w, H: h}, name: name, size: image.Pt(imgW, imgH)}
+	if crop {
+		entry.Crop = &l.Crop
+	}
+	g.index = append(g.index, entry)
+
+	g.beginLayer(opts.Layer)
+	if opts.BackgroundColor != nil {
+		content := opts.contentRect(area)
+		setFillColor(g.pdf, g.color(opts.BackgroundColor))
+		g.pdf.Rect(content.X, content.Y, content.W, content.H, "F")
+	}
+	if opts.CheckerboardBackground {
+		g.drawCheckerboard(opts.contentRect(area))
+	}
+	if opts.AltText != "" {
+		g.beginFigure(opts.AltText)
+	}
+	if opts.EdgeExtend && opts.Mode == Fit && !crop && opts.Rotate == 0 {
+		g.drawEdgeExtension(name, opt, opts.contentRect(area), x, y, w, h)
+	}
+	if crop {
+		clip := opts.clipRect(area, x, y, w, h)
+		g.pdf.ClipRect(clip.X, clip.Y, clip.W, clip.H, false)
+	}
+	if opts.Rotate != 0 {
+		px, py := opts.pivot(x, y, w, h)
+		g.pdf.TransformBegin()
+		// gofpdf rotates counter-clockwise.
+		g.pdf.TransformRotate(-opts.Rotate, px, py)
+	}
+	drawn := opts.drawnRect(x, y, w, h)
+	g.pdf.ImageOptions(name, drawn.X, drawn.Y, drawn.W, drawn.H, false, opt, 0, "")
+	if opts.Tint != nil {
+		g.drawTint(opts.Tint, opts.TintOpacity, Rect{X: x, Y: y, W: w, H: h})
+	}
+	if opts.Vignette > 0 {
+		g.drawVignette(opts.Vignette, Rect{X: x, Y: y, W: w, H: h})
+	}
+	if opts.Rotate != 0 {
+		g.pdf.TransformEnd()
+	}
+	if crop {
+		g.pdf.ClipEnd()
+	}
+	if opts.ScaleBar.PixelsPerUnit > 0 {
+		g.drawScaleBar(opts.ScaleBar, opts.contentRect(area).intersect(Rect{X: x, Y: y, W: w, H: h}), imgW, w/float64(imgW))
+	}
+	if opts.AltText != "" {
+		g.endFigure()
+	}
+	if caption != "" {
+		content := opts.contentRect(rect)
+		g.drawCaption(caption, Rect{X: content.X, Y: content.Y + content.H - g.captionHeight(), W: content.W, H: g.captionHeight()})
+	}
+	g.endLayer(opts.Layer)
+	if opts.layoutSize == (image.Point{}) || !opts.pixelCrop.Empty() {
+		// Shrunk previews are meant to be low resolution.
+		visible := opts.contentRect(area).intersect(drawn)
+		g.drawLowResBadge(visible, int(info.Width()), int(info.Height()), drawn.W, drawn.H)
+	}
 }
 
-func (g *Generator) AddImage(img image.Image, opts ImageOptions) error {
+// Starts a new page, drawing everything that repeats on every page.
+func (g *Generator) addPage() {
+	if g.startOnRight && g.pdf.PageNo() == 0 {
+		// The blank left-hand page facing page one.
+		g.pdf.AddPageFormat("P", gofpdf.SizeType{Wd: g.pageSize.W, Ht: g.pageSize.H})
+		g.blankPage = g.pdf.PageNo()
+	}
+	next := g.drawNextButton()
+	g.pdf.AddPageFormat("P", gofpdf.SizeType{Wd: g.pageSize.W, Ht: g.pageSize.H})
+	if next != 0 {
+		g.pdf.SetLink(next, 0, -1)
+	}
+	if g.pageBackground != nil {
+		w, h := g.pdf.GetPageSize()
+		setFillColor(g.pdf, g.color(g.pageBackground))
+		g.pdf.Rect(0, 0, w, h, "F")
+	}
+	g.drawBands()
+	g.drawPrevButton()
+	if g.pageNumbers != nil {
+		g.numberedPages = append(g.numberedPages, numberedPage{page: g.pdf.PageNo(), size: g.pageSize, opts: *g.pageNumbers})
+	}
+}
+
+// Returns the options an image on the next page is laid out with, in points: the cover's on the first
+// page, with the gutter of the page added.
+func (g *Generator) nextPageOptions(opts ImageOptions) ImageOptions {
+	return g.optsInPoints(g.pageOptions(opts)).withGutter(g.nextBookPage())
+}
+
+func (g *Generator) addImage(typ string, r io.Reader, opts ImageOptions) error {
+	opts = g.nextPageOptions(opts)
+	var data []byte
+	if (g.pageSizeFromDPI || g.aSeriesDPI > 0 || g.autoOrient) && r != nil {
+		var err error
+		if data, err = io.ReadAll(r); err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	if g.pageSizeFromDPI && data != nil {
+		if size, ok := dpiPageSize(data); ok {
+			defer func(size PageSize) { g.pageSize = size }(g.pageSize)
+			g.pageSize = g.pageSizeSnapping.snap(size)
+		}
+	}
+	if g.aSeriesDPI > 0 {
+		if size, ok := imageSize(data, opts); ok {
+			defer func(size PageSize) { g.pageSize = size }(g.pageSize)
+			g.pageSize = aSeriesPageSize(size, g.aSeriesDPI)
+		}
+	}
+	if g.autoOrient {
+		if size, ok := imageSize(data, opts); ok && size.X != size.Y && (size.X > size.Y) != (g.pageSize.W > g.pageSize.H) {
+			defer func(size PageSize) { g.pageSize = size }(g.pageSize)
+			g.pageSize = g.pageSize.Rotate()
+		}
+	}
+	// Registered first, so that images failing to load don't leave an empty page behind.
+	img, err := g.registerImage(typ, r, g.pageRect(), opts)
+	if err != nil {
+		return err
+	}
+	g.addPage()
+	g.drawImage(img)
+	return nil
+}
+
+// Size of a checkerboard square in points.
+const checkerSize = 8
+
+// Fills rect (in points) with a light gray checkerboard.
+func (g *Generator) drawCheckerboard(rect Rect) {
+	g.pdf.SetFillColor(0xff, 0xff, 0xff)
+	g.pdf.Rect(rect.X, rect.Y, rect.W, rect.H, "F")
+	g.pdf.SetFillColor(0xcc, 0xcc, 0xcc)
+	for row := 0; float64(row)*checkerSize < rect.H; row++ {
+		for col := row % 2; float64(col)*checkerSize < rect.W; col += 2 {
+			x, y := float64(col)*checkerSize, float64(row)*checkerSize
+			g.pdf.Rect(rect.X+x, rect.Y+y, min(checkerSize, rect.W-x), min(checkerSize, rect.H-y), "F")
+		}
+	}
+}
+
+// Fills the bars beside the image placed at x, y, w, h within rect with copies of the image, alternately
+// mirrored so that each copy continues the edge of its neighbor.
+func (g *Generator) drawEdgeExtension(name string, opt gofpdf.ImageOptions, rect Rect, x, y, w, h float64) {
+	const epsilon = 0.01
+	horizontal := rect.W-w > epsilon
+	bars := [2]Rect{
+		{X: x, Y: rect.Y, W: w, H: y - rect.Y},
+		{X: x, Y: y + h, W: w, H: rect.Y + rect.H - y - h},
+	}
+	if horizontal {
+		bars = [2]Rect{
+			{X: rect.X, Y: y, W: x - rect.X, H: h},
+			{X: x + w, Y: y, W: rect.X + rect.W - x - w, H: h},
+		}
+	}
+	for side, bar := range bars {
+		if bar.W <= epsilon || bar.H <= epsilon {
+			continue
+		}
+		// Copies are laid out before the image on the first side and after it on the second.
+		dir := float64(2*side - 1)
+		step, extent := h, bar.H
+		if horizontal {
+			step, extent = w, bar.W
+		}
+		g.pdf.ClipRect(bar.X, bar.Y, bar.W, bar.H, false)
+		for k := 1; float64(k-1)*step < extent; k++ {
+			cx, cy := x, y
+			if horizontal {
+				cx += dir * float64(k) * w
+			} else {
+				cy += dir * float64(k) * h
+			}
+			if k%2 == 0 {
+				g.pdf.ImageOptions(name, cx, cy, w, h, false, opt, 0, "")
+				continue
+			}
+			g.pdf.TransformBegin()
+			if horizontal {
+				g.pdf.TransformMirrorHorizontal(cx + w/2)
+			} else {
+				g.pdf.TransformMirrorVertical(cy + h/2)
+			}
+			g.pdf.ImageOptions(name, cx, cy, w, h, false, opt, 0, "")
+			g.pdf.TransformEnd()
+		}
+		g.pdf.ClipEnd()
+	}
+}
+
+// Fills rect (in points) with a semi-transparent color.
+func (g *Generator) drawTint(c color.Color, opacity float64, rect Rect) {
+	if opacity <= 0 || opacity > 1 {
+		opacity = 0.5
+	}
+	g.pdf.SetAlpha(opacity, "Normal")
+	setFillColor(g.pdf, g.color(c))
+	g.pdf.Rect(rect.X, rect.Y, rect.W, rect.H, "F")
+	g.pdf.SetAlpha(1, "Normal")
+}
+
+// Darkens rect (in points) towards its corners by multiplying it with a radial gradient, which leaves
+// the center as it is.
+func (g *Generator) drawVignette(strength float64, rect Rect) {
+	g.pdf.SetAlpha(min(strength, 1), "Multiply")
+	g.pdf.RadialGradient(rect.X, rect.Y, rect.W, rect.H, 0xff, 0xff, 0xff, 0, 0, 0, 0.5, 0.5, 0.5, 0.5, math.Sqrt2/2)
+	g.pdf.SetAlpha(1, "Normal")
+}
+
+func setFillColor(pdf *gofpdf.Fpdf, c color.Color) {
+	r, g, b, _ := c.RGBA()
+	pdf.SetFillColor(int(r>>8), int(g>>8), int(b>>8))
+}
+
+func setDrawColor(pdf *gofpdf.Fpdf, c color.Color) {
+	r, g, b, _ := c.RGBA()
+	pdf.SetDrawColor(int(r>>8), int(g>>8), int(b>>8))
+}
+
+// Returned when adding a nil image.
+var ErrNilImage = errors.New("p4p: image is nil")
+
+// Reports whether the image is nil, including nil pointers of concrete image types, e.g. (*image.RGBA)(nil).
+func isNilImage(img image.Image) bool {
+	if img == nil {
+		return true
+	}
+	v := reflect.ValueOf(img)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// Encodes the image as PNG if it has transparency and as JPEG otherwise, unless opts force either.
+// CMYK images are encoded as placeholders for lossless DeviceCMYK images instead; see cmykType.
+func encodeImage(img image.Image, opts ImageOptions) (typ string, b *bytes.Buffer, err error) {
+	if isNilImage(img) {
+		return "", nil, ErrNilImage
+	}
+	if cmyk, ok := img.(*image.CMYK); ok {
+		b, err := encodeCMYK(cmyk)
+		if err != nil {
+			return "", nil, err
+		}
+		return cmykType, b, nil
+	}
 	hasAlpha := true
-	if opImg, ok := img.(interface {
-		Opaque() bool
-	}); ok {
-		hasAlpha = !opImg.Opaque()
+	switch {
+	case opts.AssumeAlpha:
+	case opts.AssumeOpaque:
+		// Not scanned, whatever transparency there is gets flattened.
+		hasAlpha = false
+		img = flatten(img, opts)
+	default:
+		if opImg, ok := img.(interface {
+			Opaque() bool
+		}); ok {
+			hasAlpha = !opImg.Opaque()
+		}
 	}
-	var typ string
-	var b bytes.Buffer
+	b = new(bytes.Buffer)
 	if hasAlpha {
 		typ = "png"
-		if err := png.Encode(&b, img); err != nil {
-			return err
+		if err := png.Encode(b, img); err != nil {
+			return "", nil, err
 		}
 	} else {
 		typ = "jpeg"
-		if err := jpeg.Encode(&b, img, nil); err != nil {
+		var o *jpeg.Options
+		if opts.JPEGQuality > 0 {
+			o = &jpeg.Options{Quality: min(opts.JPEGQuality, 100)}
+		}
+		if err := jpeg.Encode(b, img, o); err != nil {
+			return "", nil, err
+		}
+	}
+	return typ, b, nil
+}
+
+// Returns the image drawn over a background of opts.FlattenColor, or the image itself if it cannot
+// have transparency.
+func flatten(img image.Image, opts ImageOptions) image.Image {
+	switch img.(type) {
+	case *image.YCbCr, *image.Gray, *image.Gray16, *image.CMYK:
+		return img
+	}
+	c := opts.FlattenColor
+	if c == nil {
+		c = opts.BackgroundColor
+	}
+	if c == nil {
+		c = color.White
+	}
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// Returns the part of the image within r, copying it if the image does not support sub-images.
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if img, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return img.SubImage(r)
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+// Returns the image scaled to w by h pixels, averaging all source pixels covered by a destination pixel.
+// Meant for downscaling; upscaling repeats pixels.
+func resize(img image.Image, w, h int) *image.NRGBA {
+	src := image.NewNRGBA(img.Bounds())
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy1, sy2 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			sx1, sx2 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, b, a, n uint32
+			for sy := sy1; sy < sy2; sy++ {
+				for sx := sx1; sx < sx2; sx++ {
+					p := src.Pix[sy*src.Stride+4*sx:]
+					// Weight colors by alpha, so transparent pixels don't bleed their color.
+					pa := uint32(p[3])
+					r += uint32(p[0]) * pa
+					g += uint32(p[1]) * pa
+					b += uint32(p[2]) * pa
+					a += pa
+					n++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+4*x:]
+			if a > 0 {
+				d[0], d[1], d[2] = uint8(r/a), uint8(g/a), uint8(b/a)
+			}
+			d[3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Adds pages showing consecutive slices of the image scaled to the page height.
+func (g *Generator) addFlowHorizontal(img image.Image, opts ImageOptions) error {
+	opts = g.optsInPoints(opts)
+	page := g.pageRect()
+	area := opts.contentRect(page)
+	b := img.Bounds()
+	// The caption goes below the last slice only, but all slices leave room for it to line up.
+	captionH := 0.0
+	if opts.Caption != "" {
+		captionH = g.captionHeight()
+	}
+	// Points per pixel.
+	scale := (area.H - captionH) / float64(b.Dy())
+	sliceW := max(int(area.W/scale), 1)
+
+	sliceOpts := opts
+	sliceOpts.Mode = Fit
+	sliceOpts.Scale = 0
+	sliceOpts.FlowHorizontal = false
+	m := opts.margins()
+	for x := b.Min.X; x < b.Max.X; x += sliceW {
+		end := min(x+sliceW, b.Max.X)
+		slice := subImage(img, image.Rect(x, b.Min.Y, end, b.Max.Y))
+		typ, data, err := encodeImage(slice, opts)
+		if err != nil {
+			return err
+		}
+		// Exactly the size of the slice, so the last one stays flush with the left edge.
+		rect := page
+		rect.W = m.Left + float64(slice.Bounds().Dx())*scale + m.Right
+		rect.H -= captionH
+		sliceOpts.Caption, sliceOpts.AltText = "", ""
+		if x == b.Min.X {
+			sliceOpts.AltText = opts.AltText
+		}
+		if end == b.Max.X {
+			sliceOpts.Caption = opts.Caption
+			rect.H += captionH
+		}
+		g.addPage()
+		if err := g.placeImage(typ, data, rect, sliceOpts); err != nil {
 			return err
 		}
 	}
-	g.addImage(typ, &b, opts)
+	return nil
+}
+
+// Space between columns, in points.
+const columnGap = 12
+
+// Adds pages showing consecutive slices of the image scaled to the column width, in opts.Columns columns.
+func (g *Generator) addColumns(img image.Image, opts ImageOptions) error {
+	opts = g.optsInPoints(opts)
+	page := g.pageRect()
+	area := opts.contentRect(page)
+	n := max(opts.Columns, 1)
+	colW := (area.W - columnGap*float64(n-1)) / float64(n)
+	b := img.Bounds()
+	// Points per pixel.
+	scale := colW / float64(b.Dx())
+	sliceH := max(int(area.H/scale), 1)
+	// The caption goes below the last slice only, which must leave room for it.
+	captionH := 0.0
+	if opts.Caption != "" {
+		captionH = g.captionHeight()
+	}
+	lastH := max(int((area.H-captionH)/scale), 1)
+
+	sliceOpts := opts
+	sliceOpts.Mode = Fit
+	sliceOpts.Scale = 0
+	sliceOpts.Columns = 0
+	sliceOpts.SplitTall = false
+	m := opts.margins()
+	for i, y := 0, b.Min.Y; y < b.Max.Y; i++ {
+		end := min(y+sliceH, b.Max.Y)
+		if end == b.Max.Y && end-y > lastH {
+			end = y + lastH
+		}
+		if opts.SplitTall && end < b.Max.Y {
+			end = whitespaceSplit(img, y, end)
+		}
+		slice := subImage(img, image.Rect(b.Min.X, y, b.Max.X, end))
+		sliceOpts.Caption, sliceOpts.AltText = "", ""
+		if y == b.Min.Y {
+			sliceOpts.AltText = opts.AltText
+		}
+		if end == b.Max.Y {
+			sliceOpts.Caption = opts.Caption
+		}
+		y = end
+		typ, data, err := encodeImage(slice, opts)
+		if err != nil {
+			return err
+		}
+		col := i % n
+		if col == 0 {
+			g.addPage()
+		}
+		// Exactly the size of the slice, so the last one stays flush with the top edge.
+		rect := Rect{
+			X: area.X + float64(col)*(colW+columnGap) - m.Left,
+			Y: page.Y,
+			W: m.Left + colW + m.Right,
+			H: m.Top + float64(slice.Bounds().Dy())*scale + m.Bottom,
+		}
+		if sliceOpts.Caption != "" {
+			rect.H += captionH
+		}
+		if err := g.placeImage(typ, data, rect, sliceOpts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reports whether the image must be decoded to be laid out, rather than embedded as is.
+func (opts ImageOptions) needsDecoding() bool {
+	return opts.FlowHorizontal || opts.Columns > 1 || opts.SplitTall || opts.TrimBorder
+}
+
+// Adds the image as a new page, or several with FlowHorizontal, Columns or SplitTall. AddImage, AddImageFile,
+// AddImageBytes and AddImageReader may be called concurrently, e.g. from a pool of goroutines decoding
+// images; the pages then follow the order in which the calls get their turn, which is nondeterministic.
+// Other methods must not be called concurrently with them. An *image.CMYK is embedded losslessly,
+// keeping its CMYK samples.
+func (g *Generator) AddImage(img image.Image, opts ImageOptions) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.addDecodedImage(img, opts)
+}
+
+func (g *Generator) addDecodedImage(img image.Image, opts ImageOptions) error {
+	opts = g.withQuality(opts)
+	if isNilImage(img) {
+		return ErrNilImage
+	}
+	if opts.TrimBorder {
+		img = subImage(img, trimmedBounds(img))
+	}
+	if opts.FlowHorizontal {
+		return g.addFlowHorizontal(img, opts)
+	}
+	if opts.Columns > 1 || opts.SplitTall {
+		return g.addColumns(img, opts)
+	}
+	if opts.cropsPixels() {
+		opts.decoded = img
+		return g.addImage("", nil, opts)
+	}
+	typ, b, err := encodeImage(img, opts)
+	if err != nil {
+		return err
+	}
+	return g.addImage(typ, b, opts)
+}
+
+type DrawOrder int
+
+const (
+	// Draw images in slice order, so later images end up on top of earlier ones.
+	BackToFront DrawOrder = iota
+	// Draw images in reverse slice order, so earlier images end up on top of later ones.
+	FrontToBack
+)
+
+// Adds a single page with each image laid out within the rectangle (in points) at the same index.
+// Overlapping images are stacked according to order.
+func (g *Generator) AddImagesAtRects(imgs []image.Image, rects []Rect, order DrawOrder, opts ImageOptions) error {
+	if len(imgs) != len(rects) {
+		return errors.New("p4p: number of images and rects differ")
+	}
+	type encoded struct {
+		typ string
+		b   *bytes.Buffer
+	}
+	encs := make([]encoded, len(imgs))
+	for i, img := range imgs {
+		typ, b, err := encodeImage(img, opts)
+		if err != nil {
+			return err
+		}
+		encs[i] = encoded{typ, b}
+	}
+	// Registered in drawing order before the page is added, so that images failing to load don't
+	// leave an empty page behind.
+	registered := make([]*registeredImage, len(encs))
+	for i := range encs {
+		if order == FrontToBack {
+			i = len(encs) - 1 - i
+		}
+		var err error
+		if registered[i], err = g.registerImage(encs[i].typ, encs[i].b, rects[i], opts); err != nil {
+			return err
+		}
+	}
+	g.addPage()
+	for i := range registered {
+		if order == FrontToBack {
+			i = len(registered) - 1 - i
+		}
+		g.drawImage(registered[i])
+	}
 	return nil
 }
 
 func (g *Generator) AddImageFile(path string, opts ImageOptions) error {
-	f, err := os.Open(path)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if opts.FilenameHints {
+		opts.FilenameHints = false
+		if size, ok := filenamePageSize(path, g.pageSize); ok {
+			return g.withPageSize(size, func() error { return g.addImageFile(path, opts) })
+		}
+	}
+	return g.addImageFile(path, opts)
+}
+
+func (g *Generator) addImageFile(path string, opts ImageOptions) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	g.addImage(strings.TrimPrefix(filepath.Ext(path), "."), f, opts)
+	g.checkColorProfile(path, data)
+	defer g.recordGeoLocation(g.pdf.PageNo(), data)
+	if opts.source == "" {
+		opts.source = path
+	}
+	opts.Caption = g.fileCaption(path, opts)
+	ext := filepath.Ext(path)
+	// gofpdf doesn't read WebP, so it is re-encoded like any decoded image, and neither does it turn
+	// images upright.
+	if opts.needsDecoding() || strings.EqualFold(ext, ".webp") || exifOrientation(data) != 1 {
+		img, err := g.decodeOriented(data)
+		if err != nil {
+			return err
+		}
+		return g.addDecodedImage(img, opts)
+	}
+	return g.addImage(strings.TrimPrefix(ext, "."), bytes.NewReader(data), opts)
+}
+
+// An image along with the options it is added with; see AddImagesWithOptions.
+type ImageWithOptions struct {
+	Img  image.Image
+	Opts ImageOptions
+}
+
+// Adds each image as a new page, in order, laid out with its own options; stops at the first error.
+func (g *Generator) AddImagesWithOptions(items []ImageWithOptions) error {
+	for _, item := range items {
+		if err := g.AddImage(item.Img, item.Opts); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Rough per-object overheads of the PDF structure, in bytes.
+const (
+	docOverhead   = 1024
+	pageOverhead  = 256
+	imageOverhead = 256
+)
+
+// Returns a rough estimate of the size of the PDF in bytes if it were written now.
+func (g *Generator) EstimateSize() int64 {
+	return g.imageBytes +
+		docOverhead +
+		int64(g.pdf.PageCount())*pageOverhead +
+		int64(g.imageIndex)*imageOverhead
+}
+
+// Returns the number of pages added so far.
+func (g *Generator) PageCount() int {
+	return g.pdf.PageCount()
+}
+
+// Returned when writing the document, or drawing onto the current page, before any page was added.
+var ErrNoPage = errors.New("p4p: no page added yet")
+
+// Writes the document to w. Returns ErrNoPage, writing nothing, if no page was added.
 func (g *Generator) Write(w io.Writer) error {
-	return g.pdf.Output(w)
+	if g.PageCount() == 0 {
+		return ErrNoPage
+	}
+	g.drawPageNumbers()
+	if !g.amendsOutput() {
+		return g.pdf.Output(w)
+	}
+	var b bytes.Buffer
+	if err := g.pdf.Output(&b); err != nil {
+		return err
+	}
+	p, err := parsePDF(b.Bytes())
+	if err != nil {
+		return err
+	}
+	g.amend(p)
+	if g.objectStreams {
+		return p.writeCompact(w)
+	}
+	return p.write(w)
+}
+
+// Sets the creation and modification dates recorded in the document information, written in the
+// times' own time zone, with its offset. A zero time stands for the time the document is written, the
+// default.
+func (g *Generator) SetDates(created, modified time.Time) {
+	g.pdf.SetCreationDate(created)
+	g.pdf.SetModificationDate(modified)
+	g.created, g.modified = created, modified
+}
+
+// Adds the time zone offsets gofpdf leaves out to the dates set with SetDates.
+func (g *Generator) putDates(p *pdfFile) {
+	m := infoRe.FindSubmatch(p.trailer)
+	if m == nil {
+		return
+	}
+	n := atoi(m[1])
+	for key, t := range map[string]time.Time{"CreationDate": g.created, "ModDate": g.modified} {
+		if t.IsZero() {
+			continue
+		}
+		re := regexp.MustCompile(`(/` + key + ` \(D:\d{14})\)`)
+		p.objs[n] = re.ReplaceAll(p.objs[n], []byte("${1}"+dateOffset(t)+")"))
+	}
+}
+
+// Returns the offset of the time's zone from UTC as written in PDF dates, e.g. +01'00'.
+func dateOffset(t time.Time) string {
+	_, off := t.Zone()
+	sign := "+"
+	if off < 0 {
+		sign, off = "-", -off
+	}
+	return fmt.Sprintf("%s%02d'%02d'", sign, off/3600, off/60%60)
 }
 
+// Document information shown by viewers, e.g. in their document properties; see SetMetadata.
+type Metadata struct {
+	Title   string
+	Author  string
+	Subject string
+	// Keywords, e.g. separated by commas.
+	Keywords string
+	// Application the document was created with.
+	Creator string
+}
+
+// Sets the document information, replacing any set before. Empty fields are left out.
+func (g *Generator) SetMetadata(m Metadata) {
+	set := func(f func(s string, isUTF8 bool), s string) {
+		// gofpdf writes UTF-8 text as UTF-16, which plain ASCII doesn't need.
+		f(s, strings.ContainsFunc(s, func(r rune) bool { return r >= 0x80 }))
+	}
+	set(g.pdf.SetTitle, m.Title)
+	set(g.pdf.SetAuthor, m.Author)
+	set(g.pdf.SetSubject, m.Subject)
+	set(g.pdf.SetKeywords, m.Keywords)
+	set(g.pdf.SetCreator, m.Creator)
+}
+
+// Sets whether to write a cross-reference stream and pack objects into object streams (requires PDF 1.5),
+// which makes documents with many small objects smaller (default: false).
+func (g *Generator) SetObjectStreams(enabled bool) {
+	g.objectStreams = enabled
+}
+
+// Writes the document to the file at path. Returns ErrNoPage, without creating the file, if no page was added.
 func (g *Generator) WriteFile(path string) error {
+	if g.PageCount() == 0 {
+		return ErrNoPage
+	}
 	f, err := os.Create(path)
 	if err != nil {
 		return err
@@ -256,3 +1692,14 @@ func (g *Generator) WriteFile(path string) error {
 	defer f.Close()
 	return g.Write(f)
 }
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,20 @@
+package p4p
+
+import (
+	"testing"
+)
+
+func TestSetImageNamePrefix(t *testing.T) {
+	g := NewGenerator(A4())
+	g.SetImageNamePrefix("custom_")
+	if err := g.AddImageFile("gophers/gopher.png", ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// Image names only exist in gofpdf, the PDF references images by a hash of their data.
+	if g.pdf.GetImageInfo("custom_image_0") == nil {
+		t.Fatal("image not registered with custom prefix")
+	}
+	if g.pdf.GetImageInfo("p4p_image_0") != nil {
+		t.Fatal("image registered with default prefix")
+	}
+}
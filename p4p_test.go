@@ -1,11 +1,22 @@
 package p4p_test
 
 import (
+	"bytes"
+	"compress/zlib"
 	"image"
+	"image/color"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	p4p "github.com/pic4pdf/lib-p4p"
 )
@@ -60,3 +71,1161 @@ func TestWriteFile(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestPageForPrintWidth(t *testing.T) {
+	pageSize, opts, ok := p4p.PageForPrintWidth(20, 1, p4p.Centimeter, 316, 317)
+	if !ok {
+		t.Fatal("no page size found")
+	}
+	if pageSize != p4p.Tabloid() {
+		t.Fatal("expected tabloid page size, got:", pageSize)
+	}
+	x, _, w, _, _, _, _, _, crop := p4p.Render(pageSize, p4p.Centimeter, 316, 317, opts)
+	if math.Abs(w-20) > 1e-9 {
+		t.Fatal("expected a width of 20cm, got:", w)
+	}
+	if x < 1 || crop {
+		t.Fatal("image placed outside of margins")
+	}
+}
+
+func TestAddImagesAtRectsOrder(t *testing.T) {
+	imgs := []image.Image{decodeFile(t, "gophers/gopher.png"), decodeFile(t, "gophers/gopher2.png")}
+	rects := []p4p.Rect{{X: 0, Y: 0, W: 200, H: 200}, {X: 100, Y: 100, W: 200, H: 200}}
+	for _, order := range []p4p.DrawOrder{p4p.BackToFront, p4p.FrontToBack} {
+		g := p4p.NewGenerator(p4p.A4())
+		if err := g.AddImagesAtRects(imgs, rects, order, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+		pages := pageContents(t, writePDF(t, g))
+		if len(pages) != 1 {
+			t.Fatal("expected 1 page, got:", len(pages))
+		}
+		draws := imageDraws(pages[0])
+		if len(draws) != 2 {
+			t.Fatal("expected 2 images, got:", len(draws))
+		}
+		// Last drawn image is on top.
+		top := draws[1]
+		if order == p4p.BackToFront && top.X < 100 || order == p4p.FrontToBack && top.X >= 100 {
+			t.Fatal("wrong image on top for order", order, "got:", draws)
+		}
+	}
+}
+
+func TestAddImagesAtRectsFailure(t *testing.T) {
+	// The second image is embedded as a 16-bit PNG, which gofpdf cannot read.
+	imgs := []image.Image{decodeFile(t, "gophers/gopher.png"), image.NewNRGBA64(image.Rect(0, 0, 10, 10))}
+	rects := []p4p.Rect{{W: 200, H: 200}, {W: 200, H: 200}}
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImagesAtRects(imgs, rects, p4p.BackToFront, p4p.ImageOptions{}); err == nil {
+		t.Fatal("expected an error for the image gofpdf cannot read")
+	}
+	if err := g.AddImage(imgs[0], p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if pages := pageContents(t, writePDF(t, g)); len(pages) != 1 {
+		t.Fatal("expected no page for the failed images, got:", len(pages))
+	}
+}
+
+func decodeFile(t *testing.T, path string) image.Image {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func writePDF(t *testing.T, g *p4p.Generator) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := g.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// Returns the decompressed content stream of each page, in page order.
+func pageContents(t *testing.T, pdf []byte) []string {
+	t.Helper()
+	var pages []string
+	for _, m := range regexp.MustCompile(`/Contents (\d+) 0 R`).FindAllSubmatch(pdf, -1) {
+		obj := bytes.Index(pdf, []byte("\n"+string(m[1])+" 0 obj\n"))
+		if obj < 0 {
+			t.Fatal("missing content object", string(m[1]))
+		}
+		start := obj + bytes.Index(pdf[obj:], []byte("stream\n")) + len("stream\n")
+		end := start + bytes.Index(pdf[start:], []byte("\nendstream"))
+		r, err := zlib.NewReader(bytes.NewReader(pdf[start:end]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, string(data))
+	}
+	return pages
+}
+
+// Returns the placement of each image drawn in a content stream, in PDF coordinates.
+func imageDraws(content string) []p4p.Rect {
+	var rects []p4p.Rect
+	for _, m := range regexp.MustCompile(`q ([-\d.]+) 0 0 ([-\d.]+) ([-\d.]+) ([-\d.]+) cm /I\w+ Do Q`).FindAllStringSubmatch(content, -1) {
+		var v [4]float64
+		for i := range v {
+			v[i], _ = strconv.ParseFloat(m[i+1], 64)
+		}
+		rects = append(rects, p4p.Rect{X: v[2], Y: v[3], W: v[0], H: v[1]})
+	}
+	return rects
+}
+
+func TestPageCount(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	if n := g.PageCount(); n != 0 {
+		t.Fatal("expected 0 pages, got:", n)
+	}
+	if err := g.Write(io.Discard); err != p4p.ErrNoPage {
+		t.Fatal("expected ErrNoPage, got:", err)
+	}
+	path := filepath.Join(t.TempDir(), "empty.pdf")
+	if err := g.WriteFile(path); err != p4p.ErrNoPage {
+		t.Fatal("expected ErrNoPage, got:", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected no file to be written, got:", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := g.PageCount(); n != 2 {
+		t.Fatal("expected 2 pages, got:", n)
+	}
+	if err := g.Write(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A5())
+	g.SetMetadata(p4p.Metadata{Title: "First"})
+	for i := 0; i < 2; i++ {
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Caption: "Gopher"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := writePDF(t, g)
+
+	g.Reset()
+	if n := g.PageCount(); n != 0 {
+		t.Fatal("expected 0 pages after Reset, got:", n)
+	}
+	if err := g.Write(io.Discard); err != p4p.ErrNoPage {
+		t.Fatal("expected ErrNoPage after Reset, got:", err)
+	}
+	if err := g.AddImageFile("gophers/gopher1.jpg", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	second := writePDF(t, g)
+	if n := len(pageContents(t, second)); n != 1 {
+		t.Fatal("expected 1 page in the second document, got:", n)
+	}
+	if bytes.Contains(second, []byte("/Title")) || bytes.Contains(second, []byte("Gopher")) {
+		t.Fatal("expected no metadata or captions of the first document")
+	}
+	if !bytes.Contains(first, []byte("/MediaBox [0 0 420.94 595.28]")) || !bytes.Contains(second, []byte("/MediaBox [0 0 420.94 595.28]")) {
+		t.Fatal("expected both documents on A5 pages")
+	}
+	paths, err := p4p.ExtractImages(bytes.NewReader(second), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 {
+		t.Fatal("expected 1 image in the second document, got:", len(paths))
+	}
+}
+
+func TestJPEGQuality(t *testing.T) {
+	img := decodeFile(t, "gophers/gopher1.jpg")
+	size := func(quality int) int {
+		g := p4p.NewGenerator(p4p.A4())
+		if err := g.AddImage(img, p4p.ImageOptions{JPEGQuality: quality}); err != nil {
+			t.Fatal(err)
+		}
+		return len(writePDF(t, g))
+	}
+	low, def, high := size(50), size(0), size(95)
+	if !(low < def && def < high) {
+		t.Fatal("expected the output to grow with the JPEG quality, got sizes:", low, def, high)
+	}
+}
+
+func TestConcurrentAddImage(t *testing.T) {
+	data, err := os.ReadFile("gophers/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := p4p.NewGenerator(p4p.A4())
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// A distinct image for each goroutine, so none are shared.
+			img := image.NewGray(image.Rect(0, 0, 10+i, 10))
+			errs <- g.AddImage(img, p4p.ImageOptions{})
+			errs <- g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{})
+			errs <- g.AddImageBytes(data, p4p.ImageOptions{Mode: p4p.Fit})
+			errs <- g.AddImageReader(bytes.NewReader(data), p4p.ImageOptions{})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := g.PageCount(); n != 4*8 {
+		t.Fatal("expected", 4*8, "pages, got:", n)
+	}
+	for i, page := range pageContents(t, writePDF(t, g)) {
+		if len(imageDraws(page)) != 1 {
+			t.Fatal("expected one image on page", i+1, "got:", page)
+		}
+	}
+}
+
+func TestAddImagesWithOptions(t *testing.T) {
+	img := decodeFile(t, "gophers/gopher.png")
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImagesWithOptions([]p4p.ImageWithOptions{
+		{Img: img, Opts: p4p.ImageOptions{Mode: p4p.Center}},
+		{Img: img, Opts: p4p.ImageOptions{Mode: p4p.Fit, Align: p4p.Align{H: p4p.Left}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 pages, got:", len(pages))
+	}
+	center, fit := imageDraws(pages[0]), imageDraws(pages[1])
+	if len(center) != 1 || center[0].W != 190 || center[0].H != 283 {
+		t.Error("expected the first image at its size, got:", center)
+	}
+	if len(fit) != 1 || fit[0].X != 0 || math.Abs(fit[0].H-841.89) > 0.01 {
+		t.Error("expected the second image to fit the page height at the left edge, got:", fit)
+	}
+}
+
+func TestAddNilImage(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImage(nil, p4p.ImageOptions{}); err != p4p.ErrNilImage {
+		t.Fatal("expected ErrNilImage, got:", err)
+	}
+	// A nil pointer of a concrete image type is no image either.
+	for i, opts := range []p4p.ImageOptions{{}, {TrimBorder: true}, {Columns: 2}} {
+		if err := g.AddImage((*image.RGBA)(nil), opts); err != p4p.ErrNilImage {
+			t.Fatalf("options %d: expected ErrNilImage, got: %v", i+1, err)
+		}
+	}
+	imgs := []image.Image{decodeFile(t, "gophers/gopher.png"), (*image.NRGBA)(nil)}
+	rects := []p4p.Rect{{W: 100, H: 100}, {W: 100, H: 100}}
+	if err := g.AddImagesAtRects(imgs, rects, p4p.BackToFront, p4p.ImageOptions{}); err != p4p.ErrNilImage {
+		t.Fatal("expected ErrNilImage, got:", err)
+	}
+}
+
+func TestAlign(t *testing.T) {
+	pg := p4p.A4()
+	m := p4p.Margins{Top: 10, Right: 20, Bottom: 30, Left: 40}
+	for _, tc := range []struct {
+		mode  p4p.Mode
+		align p4p.Align
+		x, y  float64
+	}{
+		// Fit to the width of the inset page, 535.28x53.528.
+		{p4p.Fit, p4p.Align{H: p4p.Left, V: p4p.Top}, 40, 10},
+		{p4p.Fit, p4p.Align{V: p4p.Bottom}, 40, pg.H - 30 - 53.528},
+		{p4p.Fit, p4p.Align{}, 40, 10 + (pg.H-40-53.528)/2},
+		// At 72 DPI, 1000x100.
+		{p4p.Center, p4p.Align{H: p4p.Right, V: p4p.Top}, pg.W - 20 - 1000, 10},
+		{p4p.Center, p4p.Align{H: p4p.Left}, 40, 10 + (pg.H-40-100)/2},
+	} {
+		x, y, _, _, _, _, _, _, _ := p4p.Render(pg, p4p.Point, 1000, 100, p4p.ImageOptions{Mode: tc.mode, Align: tc.align, Margins: m})
+		if math.Abs(x-tc.x) > 1e-6 || math.Abs(y-tc.y) > 1e-6 {
+			t.Errorf("mode %v, align %+v: expected %v, %v, got %v, %v", tc.mode, tc.align, tc.x, tc.y, x, y)
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	pg := p4p.A4()
+	m := p4p.Margins{Top: 10, Right: 20, Bottom: 30, Left: 40}
+	// At 72 DPI, 100x200, pinned to the top-left corner within the margins and moved by the offsets.
+	opts := p4p.ImageOptions{Mode: p4p.Center, Align: p4p.Align{H: p4p.Left, V: p4p.Top}, Margins: m, OffsetX: 15, OffsetY: 5}
+	l := p4p.RenderLayout(pg, p4p.Point, 100, 200, opts)
+	if l.X != 55 || l.Y != 15 || l.W != 100 || l.H != 200 || l.NeedsCrop {
+		t.Fatalf("expected the image at 55, 15 uncropped, got: %+v", l)
+	}
+
+	// Moved 50 points out of the left and 20 out of the top of the content area.
+	opts.OffsetX, opts.OffsetY = -90, -30
+	l = p4p.RenderLayout(pg, p4p.Point, 100, 200, opts)
+	if l.X != -50 || l.Y != -20 || !l.NeedsCrop || l.Crop != image.Rect(90, 30, 100, 200) {
+		t.Fatalf("expected the image cropped to the content area, got: %+v", l)
+	}
+	opts.NoCrop = true
+	if l := p4p.RenderLayout(pg, p4p.Point, 100, 200, opts); !l.NeedsCrop {
+		t.Fatal("expected images moved out of the content area to be cropped with NoCrop")
+	}
+
+	g := p4p.NewGenerator(pg)
+	g.SetUnit(p4p.Millimeter)
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Fit, OffsetX: 10, OffsetY: 20}); err != nil {
+		t.Fatal(err)
+	}
+	b := decodeFile(t, "gophers/gopher.png").Bounds()
+	fit := p4p.RenderLayout(pg, p4p.Point, b.Dx(), b.Dy(), p4p.ImageOptions{Mode: p4p.Fit})
+	d := imageDraws(pageContents(t, writePDF(t, g))[0])[0]
+	// Drawn rects are in PDF coordinates, with the origin at the bottom left.
+	wantX, wantY := fit.X+10*float64(p4p.Millimeter), pg.H-fit.Y-fit.H-20*float64(p4p.Millimeter)
+	if math.Abs(d.X-wantX) > 0.01 || math.Abs(d.Y-wantY) > 0.01 {
+		t.Fatalf("expected the image drawn at %.2f, %.2f, got: %+v", wantX, wantY, d)
+	}
+}
+
+func TestCenterDPI(t *testing.T) {
+	pg := p4p.PageSize{W: 20, H: 20, Unit: p4p.Inch}
+	for _, tc := range []struct {
+		dpi  float64
+		w, h float64
+	}{
+		{0, 3000.0 / 72, 1500.0 / 72},
+		{72, 3000.0 / 72, 1500.0 / 72},
+		{300, 10, 5},
+	} {
+		x, y, w, h, _, _, _, _, _ := p4p.Render(pg, p4p.Inch, 3000, 1500, p4p.ImageOptions{DPI: tc.dpi})
+		if math.Abs(w-tc.w) > 1e-9 || math.Abs(h-tc.h) > 1e-9 || math.Abs(x-(20-tc.w)/2) > 1e-9 || math.Abs(y-(20-tc.h)/2) > 1e-9 {
+			t.Errorf("DPI %v: expected %vx%v centered, got %vx%v at %v,%v", tc.dpi, tc.w, tc.h, w, h, x, y)
+		}
+	}
+	// Fit ignores the resolution.
+	_, _, w, _, _, _, _, _, _ := p4p.Render(pg, p4p.Inch, 3000, 1500, p4p.ImageOptions{Mode: p4p.Fit, DPI: 300})
+	if w != 20 {
+		t.Error("expected Fit to fill the page width, got:", w)
+	}
+}
+
+func TestRenderUnknownDimension(t *testing.T) {
+	pg := p4p.PageSize{W: 400, H: 600, Unit: p4p.Point}
+	// A 4:3 image of unknown height fits the page width.
+	want := [4]float64{0, 150, 400, 300}
+	for _, tc := range []struct{ w, h int }{{800, 0}, {0, 600}} {
+		x, y, w, h, _, _, _, _, _ := p4p.Render(pg, p4p.Point, tc.w, tc.h, p4p.ImageOptions{Mode: p4p.Fit, AspectRatio: 4.0 / 3})
+		if [4]float64{x, y, w, h} != want {
+			t.Errorf("%dx%d: expected %v, got %v", tc.w, tc.h, want, [4]float64{x, y, w, h})
+		}
+	}
+	// Square without an aspect ratio.
+	_, _, w, h, _, _, _, _, _ := p4p.Render(pg, p4p.Point, 100, 0, p4p.ImageOptions{Mode: p4p.Fill})
+	if w != 600 || h != 600 {
+		t.Error("expected a 600x600 square, got:", w, h)
+	}
+	x, y, w, h, _, _, _, _, crop := p4p.Render(pg, p4p.Point, 0, 0, p4p.ImageOptions{Mode: p4p.Fit})
+	for _, v := range []float64{x, y, w, h} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			t.Fatal("expected finite coordinates without dimensions, got:", x, y, w, h)
+		}
+	}
+	if w != 0 || h != 0 || crop {
+		t.Error("expected an empty layout without dimensions, got:", w, h, crop)
+	}
+}
+
+func TestRenderLayout(t *testing.T) {
+	l := p4p.RenderLayout(p4p.A4(), p4p.Point, 316, 317, p4p.ImageOptions{Mode: p4p.Fill})
+	if !l.NeedsCrop || l.Crop != image.Rect(45, 0, 270, 317) {
+		t.Fatal("wrong crop, got:", l.Crop, l.NeedsCrop)
+	}
+	// Render returns the same layout.
+	x, y, w, h, x1, y1, x2, y2, crop := p4p.Render(p4p.A4(), p4p.Point, 316, 317, p4p.ImageOptions{Mode: p4p.Fill})
+	if got := (p4p.Layout{X: x, Y: y, W: w, H: h, Crop: image.Rect(x1, y1, x2, y2), NeedsCrop: crop}); got != l {
+		t.Fatal("Render and RenderLayout differ:", got, l)
+	}
+
+	l = p4p.RenderLayout(p4p.A4(), p4p.Point, 100, 50, p4p.ImageOptions{})
+	if l.NeedsCrop || l.Crop != image.Rect(0, 0, 100, 50) || l.X != (595.28-100)/2 || l.W != 100 {
+		t.Fatal("wrong layout of a centered image, got:", l)
+	}
+}
+
+func TestNoUpscale(t *testing.T) {
+	pg := p4p.PageSize{W: 400, H: 600, Unit: p4p.Point}
+	const eps = 1e-9
+	for _, tc := range []struct {
+		mode       p4p.Mode
+		wPx, hPx   int
+		scale      float64
+		x, y, w, h float64
+	}{
+		// Smaller, with the page's aspect ratio: kept at its size and centered.
+		{p4p.Fit, 200, 300, 0, 100, 150, 200, 300},
+		{p4p.Fill, 200, 300, 0, 100, 150, 200, 300},
+		// Exactly the page's size.
+		{p4p.Fit, 400, 600, 0, 0, 0, 400, 600},
+		{p4p.Fill, 400, 600, 0, 0, 0, 400, 600},
+		// Larger images are still shrunk.
+		{p4p.Fit, 800, 1200, 0, 0, 0, 400, 600},
+		// Smaller on one side only.
+		{p4p.Fit, 300, 1200, 0, 125, 0, 150, 600},
+		// An explicit scale still enlarges.
+		{p4p.Fit, 100, 150, 2, 100, 150, 200, 300},
+	} {
+		x, y, w, h, _, _, _, _, _ := p4p.Render(pg, p4p.Point, tc.wPx, tc.hPx, p4p.ImageOptions{Mode: tc.mode, NoUpscale: true, Scale: tc.scale})
+		if math.Abs(x-tc.x) > eps || math.Abs(y-tc.y) > eps || math.Abs(w-tc.w) > eps || math.Abs(h-tc.h) > eps {
+			t.Errorf("%v %dx%d scale %v: expected %v %v %v %v, got %v %v %v %v", tc.mode, tc.wPx, tc.hPx, tc.scale, tc.x, tc.y, tc.w, tc.h, x, y, w, h)
+		}
+	}
+}
+
+func TestFillBias(t *testing.T) {
+	_, y, _, _, x1, y1, x2, y2, crop := p4p.Render(p4p.A4(), p4p.Point, 100, 400, p4p.ImageOptions{
+		Mode:     p4p.Fill,
+		FillBias: p4p.Align{V: p4p.Top},
+	})
+	if !crop || y != 0 {
+		t.Fatal("expected image to be pinned to the top")
+	}
+	if x1 != 0 || y1 != 0 || x2 != 100 || y2 >= 400 {
+		t.Fatal("expected image to be cropped from the bottom only, got:", x1, y1, x2, y2)
+	}
+}
+
+func TestFocalPoint(t *testing.T) {
+	pg := p4p.A4()
+	const eps = 1e-9
+	// Near the top, the crop window can't move up far enough to center it.
+	_, y, _, h, _, y1, _, y2, crop := p4p.Render(pg, p4p.Point, 100, 400, p4p.ImageOptions{
+		Mode:       p4p.Fill,
+		FocalPoint: &p4p.Coord{X: 0.5, Y: 0.1},
+	})
+	if !crop || y != 0 || y1 != 0 || 0.1*400 >= float64(y2) {
+		t.Fatal("expected the crop to keep the focal point near the top, got:", y, y1, y2)
+	}
+	// Further down, it is centered.
+	_, y, _, h, _, y1, _, y2, _ = p4p.Render(pg, p4p.Point, 100, 400, p4p.ImageOptions{
+		Mode:       p4p.Fill,
+		FocalPoint: &p4p.Coord{X: 0.5, Y: 0.6},
+	})
+	if math.Abs(y+0.6*h-pg.H/2) > eps || y1 == 0 || y2 == 400 {
+		t.Fatal("expected the focal point to be centered, got:", y, y1, y2)
+	}
+	// Checked against the image's edges on both axes.
+	x, _, w, _, x1, _, x2, _, _ := p4p.Render(pg, p4p.Point, 400, 100, p4p.ImageOptions{
+		Mode:       p4p.Fill,
+		FocalPoint: &p4p.Coord{X: 1, Y: 0.5},
+	})
+	if math.Abs(x+w-pg.W) > eps || x2 < 399 || x1 == 0 {
+		t.Fatal("expected the image to be pinned to the right, got:", x, x1, x2)
+	}
+}
+
+func TestMaxFillZoom(t *testing.T) {
+	pg := p4p.A4()
+	const eps = 1e-9
+	x, y, w, h, x1, y1, x2, y2, crop := p4p.Render(pg, p4p.Point, 1000, 100, p4p.ImageOptions{
+		Mode:        p4p.Fill,
+		MaxFillZoom: 1.2,
+	})
+	// Fit would make it as wide as the page.
+	if math.Abs(w-1.2*pg.W) > eps || math.Abs(h-0.12*pg.W) > eps {
+		t.Fatal("expected the zoom to be capped at 1.2 times Fit, got:", w, h)
+	}
+	if !crop || x >= 0 || x1 == 0 || x2 == 1000 {
+		t.Fatal("expected the sides to be cropped, got:", x, x1, x2)
+	}
+	if math.Abs(y-(pg.H-h)/2) > eps || y1 != 0 || y2 != 100 {
+		t.Fatal("expected the image to be letterboxed vertically, got:", y, y1, y2)
+	}
+	// Not capped when Fill needs less zoom.
+	_, _, w, h, _, _, _, _, _ = p4p.Render(pg, p4p.Point, 100, 130, p4p.ImageOptions{
+		Mode:        p4p.Fill,
+		MaxFillZoom: 1.2,
+	})
+	if w < pg.W-eps || h < pg.H-eps {
+		t.Fatal("expected the page to be filled, got:", w, h)
+	}
+}
+
+func TestNoCrop(t *testing.T) {
+	pg := p4p.A4()
+	fit := p4p.RenderLayout(pg, p4p.Point, 400, 100, p4p.ImageOptions{Mode: p4p.Fit})
+	for _, opts := range []p4p.ImageOptions{
+		{Mode: p4p.Fill, NoCrop: true},
+		{Mode: p4p.Fill, NoCrop: true, CropMargins: p4p.Margins{Left: 10}},
+		// Too large for the page at its size.
+		{Mode: p4p.Center, Scale: 10, NoCrop: true},
+	} {
+		if l := p4p.RenderLayout(pg, p4p.Point, 400, 100, opts); l != fit {
+			t.Fatalf("expected %+v to lay out like Fit, got: %+v", opts, l)
+		}
+	}
+	if l := p4p.RenderLayout(pg, p4p.Point, 400, 100, p4p.ImageOptions{Mode: p4p.Fill}); !l.NeedsCrop {
+		t.Fatal("expected Fill without NoCrop to crop")
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	for _, path := range []string{"gophers/gopher.png", "gophers/gopher1.jpg", "gophers/gopher2.png"} {
+		if err := g.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddImage(decodeFile(t, path), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	est := g.EstimateSize()
+	actual := int64(len(writePDF(t, g)))
+	t.Log("estimated:", est, "actual:", actual)
+	if diff := math.Abs(float64(est-actual)) / float64(actual); diff > 0.25 {
+		t.Fatal("estimate off by more than 25%, estimated:", est, "actual:", actual)
+	}
+}
+
+func TestPrinterMargins(t *testing.T) {
+	margins := p4p.Margins{Top: 10, Right: 20, Bottom: 30, Left: 40}
+	pg := p4p.A4()
+	for _, mode := range []p4p.Mode{p4p.Fit, p4p.Fill} {
+		x, y, w, h, x1, y1, x2, y2, _ := p4p.Render(pg, p4p.Point, 316, 317, p4p.ImageOptions{
+			Mode:           mode,
+			PrinterMargins: margins,
+		})
+		// Visible part of the image on the page.
+		pxW, pxH := w/316, h/317
+		vx1, vy1 := x+float64(x1)*pxW, y+float64(y1)*pxH
+		vx2, vy2 := x+float64(x2)*pxW, y+float64(y2)*pxH
+		const eps = 1
+		if vx1 < margins.Left-eps || vy1 < margins.Top-eps || vx2 > pg.W-margins.Right+eps || vy2 > pg.H-margins.Bottom+eps {
+			t.Fatal("image not within printable area in mode", mode, "got:", vx1, vy1, vx2, vy2)
+		}
+	}
+}
+
+func TestMargins(t *testing.T) {
+	m := p4p.Margins{Top: 10, Right: 20, Bottom: 30, Left: 40}
+	pg := p4p.A4()
+	inW, inH := pg.W-60, pg.H-40
+	const eps = 1e-9
+
+	x, y, _, _, _, _, _, _, crop := p4p.Render(pg, p4p.Point, 100, 50, p4p.ImageOptions{Margins: m})
+	if crop || math.Abs(x-(40+inW/2-50)) > eps || math.Abs(y-(10+inH/2-25)) > eps {
+		t.Fatal("expected the image centered within the margins, got:", x, y)
+	}
+
+	x, _, w, _, _, _, _, _, _ := p4p.Render(pg, p4p.Point, 1000, 100, p4p.ImageOptions{Mode: p4p.Fit, Margins: m})
+	if math.Abs(x-40) > eps || math.Abs(w-inW) > eps {
+		t.Fatal("expected the image fit to the width within the margins, got:", x, w)
+	}
+
+	x, y, w, h, x1, y1, x2, y2, crop := p4p.Render(pg, p4p.Point, 100, 400, p4p.ImageOptions{Mode: p4p.Fill, Margins: m})
+	if !crop || math.Abs(x-40) > eps || math.Abs(w-inW) > eps || x1 != 0 || x2 != 100 {
+		t.Fatal("expected the image filled to the width within the margins, got:", x, w, x1, x2)
+	}
+	// Visible rows of the image, which is cropped at the margins.
+	pxH := h / 400
+	if vy1, vy2 := y+float64(y1)*pxH, y+float64(y2)*pxH; math.Abs(vy1-10) > pxH || math.Abs(vy2-(pg.H-30)) > pxH {
+		t.Fatal("expected the image cropped to the margins, got:", vy1, vy2)
+	}
+
+	// Added to the printer margins.
+	x, _, _, _, _, _, _, _, _ = p4p.Render(pg, p4p.Point, 1000, 100, p4p.ImageOptions{
+		Mode:           p4p.Fit,
+		Margins:        m,
+		PrinterMargins: p4p.Margins{Left: 5},
+	})
+	if math.Abs(x-45) > eps {
+		t.Fatal("expected margins inside the printer margins, got:", x)
+	}
+}
+
+func TestSetUnit(t *testing.T) {
+	pg := p4p.A4()
+	g := p4p.NewGenerator(pg)
+	g.SetUnit(p4p.Millimeter)
+	opts := p4p.ImageOptions{Mode: p4p.Fit, Margins: p4p.Margins{Top: 10, Right: 10, Bottom: 10, Left: 10}}
+	for _, add := range []func() error{
+		func() error { return g.AddImageFile("gophers/gopher1.jpg", opts) },
+		func() error { return g.AddImage(decodeFile(t, "gophers/gopher1.jpg"), opts) },
+	} {
+		if err := add(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 10mm in points.
+	m := 10 * float64(p4p.Millimeter)
+	for i, page := range pageContents(t, writePDF(t, g)) {
+		// gopher1.jpg is almost square, so it is fit to the page width.
+		d := imageDraws(page)[0]
+		if math.Abs(d.X-m) > 0.01 || math.Abs(d.W-(pg.W-2*m)) > 0.01 {
+			t.Fatal("expected 10mm margins on page", i+1, "got:", d)
+		}
+	}
+}
+
+func TestCropMargins(t *testing.T) {
+	const mm = 5
+	_, _, w, h, x1, y1, x2, y2, crop := p4p.Render(p4p.A4(), p4p.Millimeter, 1000, 500, p4p.ImageOptions{
+		Mode:        p4p.Fit,
+		CropMargins: p4p.Margins{Top: mm, Right: mm, Bottom: mm, Left: mm},
+	})
+	if !crop {
+		t.Fatal("did not detect that image must be cropped")
+	}
+	// Fit to the page width of 210mm; 5mm is 23.8 pixels.
+	if math.Abs(w-210) > 0.01 || math.Abs(h-105) > 0.01 {
+		t.Fatal("crop margins changed the layout, got:", w, h)
+	}
+	if x1 != 23 || y1 != 23 || x2 != 1000-23 || y2 != 500-23 {
+		t.Fatal("wrong crop coordinates, got:", x1, y1, x2, y2)
+	}
+}
+
+func TestSetDates(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetDates(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), time.Date(2022, 11, 12, 13, 14, 15, 0, time.FixedZone("", -(5*3600+30*60))))
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	info := regexp.MustCompile(`/Info (\d+) 0 R`).FindSubmatch(pdf)
+	if info == nil {
+		t.Fatal("missing info dictionary")
+	}
+	dict := regexp.MustCompile(`(?s)\n` + string(info[1]) + ` 0 obj\n(.*?)\nendobj`).FindSubmatch(pdf)
+	for _, want := range []string{"/CreationDate (D:20210304050607+00'00')", "/ModDate (D:20221112131415-05'30')"} {
+		if dict == nil || !bytes.Contains(dict[1], []byte(want)) {
+			t.Fatal("missing", want, "in info dictionary")
+		}
+	}
+}
+
+func TestSetMetadata(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetMetadata(p4p.Metadata{Title: "Gophers (draft)", Author: "Gopher", Keywords: "gopher, mascot"})
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	g.SetMetadata(p4p.Metadata{Title: "Gophers", Author: "Gopher", Subject: "Zoë", Creator: "p4p"})
+	pdf := writePDF(t, g)
+	for _, want := range []string{"/Title (Gophers)", "/Author (Gopher)", "/Subject (\xfe\xff\x00Z\x00o\x00\xeb)", "/Creator (p4p)"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Fatal("missing", want, "in info dictionary")
+		}
+	}
+	if bytes.Contains(pdf, []byte("/Keywords")) {
+		t.Fatal("expected the keywords to be replaced")
+	}
+}
+
+func TestObjectStreams(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		g := p4p.NewGenerator(p4p.A4())
+		g.SetObjectStreams(enabled)
+		for _, path := range []string{"gophers/gopher.png", "gophers/gopher1.jpg"} {
+			if err := g.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		pdf := writePDF(t, g)
+		xrefTable := bytes.Contains(pdf, []byte("\nxref\n")) && bytes.Contains(pdf, []byte("\ntrailer\n"))
+		xrefStream := bytes.Contains(pdf, []byte("/Type /XRef")) && bytes.Contains(pdf, []byte("/Type /ObjStm"))
+		if xrefTable == enabled || xrefStream != enabled {
+			t.Fatal("wrong cross-reference format with object streams set to", enabled)
+		}
+		if enabled && !bytes.HasPrefix(pdf, []byte("%PDF-1.5")) {
+			t.Fatal("object streams require PDF 1.5")
+		}
+	}
+}
+
+func TestFlowHorizontal(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImage(image.NewRGBA(image.Rect(0, 0, 2000, 500)), p4p.ImageOptions{FlowHorizontal: true}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	// Scaled to the page height, a page holds 353 pixels of the image width.
+	if len(pages) != 6 {
+		t.Fatal("expected 6 pages, got:", len(pages))
+	}
+	pg := p4p.A4()
+	scale := pg.H / 500
+	for i, page := range pages {
+		draws := imageDraws(page)
+		if len(draws) != 1 {
+			t.Fatal("expected one slice on page", i+1)
+		}
+		want := 353 * scale
+		if i == len(pages)-1 {
+			want = (2000 - 5*353) * scale
+		}
+		if s := draws[0]; math.Abs(s.W-want) > 0.01 || math.Abs(s.H-pg.H) > 0.01 || s.X != 0 {
+			t.Fatal("wrong slice placement on page", i+1, "got:", s)
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImage(image.NewGray(image.Rect(0, 0, 100, 2000)), p4p.ImageOptions{Columns: 2}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	pg := p4p.A4()
+	colW := (pg.W - 12) / 2
+	scale := colW / 100
+	// Scaled to the column width, a column holds 288 pixels of the image height: 7 slices in 2 columns.
+	if len(pages) != 4 {
+		t.Fatal("expected 4 pages, got:", len(pages))
+	}
+	const eps = 0.01
+	for i, page := range pages {
+		draws := imageDraws(page)
+		if want := min(2, 7-2*i); len(draws) != want {
+			t.Fatal("expected", want, "slices on page", i+1, "got:", len(draws))
+		}
+		for col, s := range draws {
+			h := 288 * scale
+			if 2*i+col == 6 {
+				h = (2000 - 6*288) * scale
+			}
+			if math.Abs(s.X-float64(col)*(colW+12)) > eps || math.Abs(s.W-colW) > eps || math.Abs(s.H-h) > eps || math.Abs(s.Y+s.H-pg.H) > eps {
+				t.Fatal("wrong slice placement in column", col+1, "on page", i+1, "got:", s)
+			}
+		}
+	}
+}
+
+func TestSplitTall(t *testing.T) {
+	// Blocks of text 30 pixels high, 10 pixels apart.
+	img := image.NewGray(image.Rect(0, 0, 100, 420))
+	for y := 0; y < 420; y++ {
+		for x := 0; x < 100; x++ {
+			c := uint8(0xff)
+			if y%40 >= 10 && x%3 == 0 {
+				c = 0
+			}
+			img.SetGray(x, y, color.Gray{Y: c})
+		}
+	}
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImage(img, p4p.ImageOptions{SplitTall: true}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	pg := p4p.A4()
+	scale := pg.W / 100
+	// Scaled to the page width, a page holds 141 pixels of the image height, which would cut through the
+	// blocks at rows 130 to 160, 250 to 280 and 370 to 400; each page ends with the gap above them instead.
+	want := []int{130, 120, 120, 50}
+	if len(pages) != len(want) {
+		t.Fatal("expected", len(want), "pages, got:", len(pages))
+	}
+	const eps = 0.01
+	for i, page := range pages {
+		draws := imageDraws(page)
+		if len(draws) != 1 {
+			t.Fatal("expected one slice on page", i+1, "got:", len(draws))
+		}
+		if s := draws[0]; math.Abs(s.H-float64(want[i])*scale) > eps || math.Abs(s.W-pg.W) > eps || math.Abs(s.Y+s.H-pg.H) > eps {
+			t.Fatalf("expected a slice of %d pixels at the top of page %d, got: %+v", want[i], i+1, s)
+		}
+	}
+}
+
+func TestCheckerboardBackground(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	// Fully transparent image.
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit, CheckerboardBackground: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	squares := strings.Count(pages[0], " re f")
+	// Half of the 8pt squares on an A4 page, plus the white base.
+	if squares < 3000 {
+		t.Fatal("expected checkerboard squares, got:", squares)
+	}
+	if strings.Index(pages[0], " re f") > strings.Index(pages[0], " Do Q") {
+		t.Fatal("checkerboard drawn on top of the image")
+	}
+	if strings.Contains(pages[1], " re f") {
+		t.Fatal("checkerboard drawn without being enabled")
+	}
+}
+
+func TestEdgeExtend(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	opts := p4p.ImageOptions{Mode: p4p.Fit, EdgeExtend: true}
+	// Bars narrower than the image on the sides, and much taller than the image above and below.
+	for _, img := range []image.Image{decodeFile(t, "gophers/gopher.png"), image.NewGray(image.Rect(0, 0, 400, 20))} {
+		if err := g.AddImage(img, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AddImage(decodeFile(t, "gophers/gopher.png"), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	const eps = 0.01
+	draws := imageDraws(pages[0])
+	if len(draws) != 3 {
+		t.Fatal("expected a mirrored copy on each side, got:", draws)
+	}
+	left, right, img := draws[0], draws[1], draws[2]
+	if math.Abs(left.X-(img.X-img.W)) > eps || math.Abs(right.X-(img.X+img.W)) > eps || left.Y != img.Y || right.Y != img.Y {
+		t.Fatal("copies not adjacent to the image, got:", draws)
+	}
+	if !strings.Contains(pages[0], " re W n") {
+		t.Fatal("copies not clipped to the bars")
+	}
+	draws = imageDraws(pages[1])
+	// Each bar is about 406pt tall, filled by 30pt high copies.
+	if len(draws) != 2*14+1 {
+		t.Fatal("expected copies filling the bars above and below, got:", len(draws))
+	}
+	for _, d := range draws {
+		if d.X != draws[0].X {
+			t.Fatal("copies not stacked vertically, got:", draws)
+		}
+	}
+	if len(imageDraws(pages[2])) != 1 {
+		t.Fatal("edges extended without being enabled")
+	}
+}
+
+func TestPageBackgroundColor(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetPageBackgroundColor(color.RGBA{B: 0xff, A: 0xff})
+	for _, opts := range []p4p.ImageOptions{{}, {BackgroundColor: color.RGBA{R: 0xff, A: 0xff}}, {}} {
+		if err := g.AddImageFile("gophers/gopher.png", opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.SetPageBackgroundColor(nil)
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	const page = "0.000 0.000 1.000 rg\n0.00 841.89 595.28 -841.89 re f"
+	const image = "1.000 0.000 0.000 rg\n0.00 841.89 595.28 -841.89 re f"
+	for i, content := range pageContents(t, writePDF(t, g)) {
+		bg := strings.Index(content, page)
+		if (bg >= 0) != (i < 3) {
+			t.Fatal("wrong page background on page", i+1)
+		}
+		if bg > strings.Index(content, " Do Q") {
+			t.Fatal("page background drawn on top of the image on page", i+1)
+		}
+		if override := strings.Index(content, image); (override >= 0) != (i == 1) || override >= 0 && override < bg {
+			t.Fatal("wrong image background on page", i+1)
+		}
+	}
+}
+
+func TestTint(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	tint := p4p.ImageOptions{Mode: p4p.Fit, Tint: color.RGBA{R: 0xff, A: 0xff}, TintOpacity: 0.3}
+	if err := g.AddImage(decodeFile(t, "gophers/gopher.png"), tint); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImage(decodeFile(t, "gophers/gopher.png"), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	if !bytes.Contains(pdf, []byte("/ca 0.300")) {
+		t.Fatal("missing graphics state with the tint opacity")
+	}
+	pages := pageContents(t, pdf)
+	img := imageDraws(pages[0])[0]
+	// The rectangle is given by its top-left corner and a negative height in PDF coordinates.
+	rect := regexp.MustCompile(`/GS(\d+) gs\n1\.000 0\.000 0\.000 rg\n([-\d.]+) ([-\d.]+) ([-\d.]+) ([-\d.]+) re f\n/GS\d+ gs`).FindStringSubmatch(pages[0])
+	if rect == nil {
+		t.Fatal("missing tint rectangle, got:", pages[0])
+	}
+	if strings.Index(pages[0], " Do Q") > strings.Index(pages[0], rect[0]) {
+		t.Fatal("tint drawn below the image")
+	}
+	var v [4]float64
+	for i := range v {
+		v[i], _ = strconv.ParseFloat(rect[i+2], 64)
+	}
+	const eps = 0.01
+	if math.Abs(v[0]-img.X) > eps || math.Abs(v[1]+v[3]-img.Y) > eps || math.Abs(v[2]-img.W) > eps || math.Abs(-v[3]-img.H) > eps {
+		t.Fatal("tint doesn't cover the image, got:", v, "image:", img)
+	}
+	if strings.Contains(pages[1], " re f") {
+		t.Fatal("tint drawn without being enabled")
+	}
+}
+
+func TestVignette(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImage(decodeFile(t, "gophers/gopher.png"), p4p.ImageOptions{Mode: p4p.Fit, Vignette: 0.4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImage(decodeFile(t, "gophers/gopher.png"), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	for _, want := range []string{"/ShadingType 3", "/ca 0.400", "/BM /Multiply"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Fatal("missing", want, "of the vignette")
+		}
+	}
+	pages := pageContents(t, pdf)
+	if sh := strings.Index(pages[0], " sh"); sh < 0 || sh < strings.Index(pages[0], " Do Q") {
+		t.Fatal("expected the vignette drawn over the image, got:", pages[0])
+	}
+	if strings.Contains(pages[1], " sh") {
+		t.Fatal("unexpected vignette on page 2")
+	}
+}
+
+func TestRotatedLayout(t *testing.T) {
+	const eps = 1e-9
+	page := p4p.PageSize{W: 300, H: 600, Unit: p4p.Point}
+	// A landscape image turned upright exactly covers the portrait page with both Fit and Fill.
+	for _, mode := range []p4p.Mode{p4p.Fit, p4p.Fill} {
+		opts := p4p.ImageOptions{Mode: mode, Rotate: 90}
+		x, y, w, h, _, _, _, _, crop := p4p.Render(page, p4p.Point, 400, 200, opts)
+		b := p4p.RotatedBounds(x, y, w, h, opts)
+		if math.Abs(w-600) > eps || math.Abs(h-300) > eps || math.Abs(b.X) > eps || math.Abs(b.Y) > eps ||
+			math.Abs(b.W-300) > eps || math.Abs(b.H-600) > eps || crop {
+			t.Errorf("mode %v: expected 600x300 image covering the page, got %vx%v with bounds %+v, crop %v", mode, w, h, b, crop)
+		}
+	}
+
+	page = p4p.PageSize{W: 300, H: 300, Unit: p4p.Point}
+	// A square turned by 45 degrees fits with its corners touching the edges...
+	opts := p4p.ImageOptions{Mode: p4p.Fit, Rotate: 45}
+	x, y, w, h, _, _, _, _, crop := p4p.Render(page, p4p.Point, 100, 100, opts)
+	b := p4p.RotatedBounds(x, y, w, h, opts)
+	if math.Abs(w-300/math.Sqrt2) > eps || math.Abs(b.X) > eps || math.Abs(b.Y) > eps || math.Abs(b.W-300) > eps || crop {
+		t.Errorf("expected the rotated square to fit the page, got %vx%v with bounds %+v, crop %v", w, h, b, crop)
+	}
+	// ...and fills the page with the page's corners touching its edges.
+	opts.Mode = p4p.Fill
+	x, y, w, h, _, _, _, _, crop = p4p.Render(page, p4p.Point, 100, 100, opts)
+	if math.Abs(w-300*math.Sqrt2) > eps || math.Abs(x+y+w/2+h/2-300) > eps || !crop {
+		t.Errorf("expected the rotated square to cover the page, got %vx%v at %v,%v, crop %v", w, h, x, y, crop)
+	}
+	// The page's corners are within the image, rotated back around its center.
+	cx, cy := x+w/2, y+h/2
+	sin, cos := math.Sincos(-45 * math.Pi / 180)
+	for _, c := range []p4p.Coord{{X: 0, Y: 0}, {X: 300, Y: 0}, {X: 0, Y: 300}, {X: 300, Y: 300}} {
+		dx, dy := c.X-cx, c.Y-cy
+		if rx, ry := dx*cos-dy*sin, dx*sin+dy*cos; math.Abs(rx) > w/2+eps || math.Abs(ry) > h/2+eps {
+			t.Errorf("page corner %v is outside the image", c)
+		}
+	}
+}
+
+func TestRotateOrigin(t *testing.T) {
+	opts := p4p.ImageOptions{Rotate: 90, RotateOrigin: &p4p.Coord{}}
+	b := p4p.RotatedBounds(10, 20, 100, 50, opts)
+	// The top edge swings down along the left edge, the left edge ends up above the old top-left corner.
+	want := p4p.Rect{X: -40, Y: 20, W: 50, H: 100}
+	const eps = 1e-9
+	if math.Abs(b.X-want.X) > eps || math.Abs(b.Y-want.Y) > eps || math.Abs(b.W-want.W) > eps || math.Abs(b.H-want.H) > eps {
+		t.Fatal("wrong bounds, got:", b)
+	}
+	opts.RotateOrigin = nil
+	b = p4p.RotatedBounds(10, 20, 100, 50, opts)
+	want = p4p.Rect{X: 35, Y: -5, W: 50, H: 100}
+	if math.Abs(b.X-want.X) > eps || math.Abs(b.Y-want.Y) > eps || math.Abs(b.W-want.W) > eps || math.Abs(b.H-want.H) > eps {
+		t.Fatal("wrong bounds for rotation around the center, got:", b)
+	}
+
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Center, Rotate: 90, RotateOrigin: &p4p.Coord{}}); err != nil {
+		t.Fatal(err)
+	}
+	// Rotation matrix for 90 degrees clockwise; gofpdf's y axis points up.
+	if !strings.Contains(pageContents(t, writePDF(t, g))[0], "0.00000 -1.00000 1.00000 0.00000") {
+		t.Fatal("missing rotation transform")
+	}
+}
+
+// An image recording whether it was scanned for transparency.
+type scannedImage struct {
+	*image.NRGBA
+	scanned bool
+}
+
+func (img *scannedImage) Opaque() bool {
+	img.scanned = true
+	return img.NRGBA.Opaque()
+}
+
+func TestAssumeOpacity(t *testing.T) {
+	opaque := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 0xff
+	}
+	transparent := image.NewNRGBA(image.Rect(0, 0, 30, 40))
+	for _, tc := range []struct {
+		img    image.Image
+		opts   p4p.ImageOptions
+		filter string
+	}{
+		{opaque, p4p.ImageOptions{}, "/DCTDecode"},
+		{opaque, p4p.ImageOptions{AssumeAlpha: true}, "/FlateDecode"},
+		{transparent, p4p.ImageOptions{}, "/FlateDecode"},
+		{transparent, p4p.ImageOptions{AssumeOpaque: true}, "/DCTDecode"},
+		{transparent, p4p.ImageOptions{AssumeOpaque: true, AssumeAlpha: true}, "/FlateDecode"},
+	} {
+		g := p4p.NewGenerator(p4p.A4())
+		if err := g.AddImage(tc.img, tc.opts); err != nil {
+			t.Fatal(err)
+		}
+		pdf := writePDF(t, g)
+		if !regexp.MustCompile(`/Subtype /Image\n[^>]*/Filter ` + tc.filter + `\n`).Match(pdf) {
+			t.Errorf("expected an image with filter %s for %+v", tc.filter, tc.opts)
+		}
+	}
+
+	// The PNG encoder checks for transparency itself, so only AssumeOpaque avoids the scan.
+	for _, opts := range []p4p.ImageOptions{{AssumeOpaque: true}, {}} {
+		img := &scannedImage{NRGBA: opaque}
+		if err := p4p.NewGenerator(p4p.A4()).AddImage(img, opts); err != nil {
+			t.Fatal(err)
+		}
+		if want := opts == (p4p.ImageOptions{}); img.scanned != want {
+			t.Errorf("expected scanned to be %v for %+v", want, opts)
+		}
+	}
+}
+
+func TestFlattenColor(t *testing.T) {
+	// Transparent apart from a black opaque square.
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{A: 0xff})
+		}
+	}
+	for _, tc := range []struct {
+		opts p4p.ImageOptions
+		want color.RGBA
+	}{
+		{p4p.ImageOptions{}, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{p4p.ImageOptions{FlattenColor: color.RGBA{0xff, 0, 0, 0xff}}, color.RGBA{0xff, 0, 0, 0xff}},
+		{p4p.ImageOptions{BackgroundColor: color.RGBA{0, 0, 0xff, 0xff}}, color.RGBA{0, 0, 0xff, 0xff}},
+	} {
+		tc.opts.AssumeOpaque = true
+		g := p4p.NewGenerator(p4p.A4())
+		if err := g.AddImage(img, tc.opts); err != nil {
+			t.Fatal(err)
+		}
+		paths, err := p4p.ExtractImages(bytes.NewReader(writePDF(t, g)), t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := decodeFile(t, paths[0])
+		for _, c := range []struct {
+			pt   image.Point
+			want color.RGBA
+		}{{image.Pt(4, 4), color.RGBA{A: 0xff}}, {image.Pt(28, 28), tc.want}} {
+			r, g, b, _ := out.At(c.pt.X, c.pt.Y).RGBA()
+			// Allow for JPEG compression.
+			if max(diff(r>>8, c.want.R), diff(g>>8, c.want.G), diff(b>>8, c.want.B)) > 0x20 {
+				t.Errorf("%+v: expected %v at %v, got %v", tc.opts, c.want, c.pt, out.At(c.pt.X, c.pt.Y))
+			}
+		}
+	}
+}
+
+func BenchmarkAddImageOpacityScan(b *testing.B) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2000, 2000))
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	for _, bc := range []struct {
+		name string
+		opts p4p.ImageOptions
+	}{
+		{"Scan", p4p.ImageOptions{}},
+		{"AssumeOpaque", p4p.ImageOptions{AssumeOpaque: true}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := p4p.NewGenerator(p4p.A4()).AddImage(img, bc.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestFillEmbedsCroppedImage(t *testing.T) {
+	pg := p4p.A4().Rotate()
+	// gopher2.png is 245x300 pixels.
+	_, _, _, _, x1, y1, x2, y2, crop := p4p.Render(pg, p4p.Point, 245, 300, p4p.ImageOptions{Mode: p4p.Fill})
+	if !crop {
+		t.Fatal("expected Fill to crop")
+	}
+	g := p4p.NewGenerator(pg)
+	if err := g.AddImageFile("gophers/gopher2.png", p4p.ImageOptions{Mode: p4p.Fill}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImage(decodeFile(t, "gophers/gopher2.png"), p4p.ImageOptions{Mode: p4p.Fill}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	paths, err := p4p.ExtractImages(bytes.NewReader(pdf), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same pixels, embedded once.
+	if len(paths) != 1 {
+		t.Fatal("expected a single embedded image, got:", len(paths))
+	}
+	// Including the pixels only partly visible.
+	b := decodeFile(t, paths[0]).Bounds()
+	if b.Dx() < x2-x1 || b.Dx() > x2-x1+1 || b.Dy() < y2-y1 || b.Dy() > y2-y1+1 || b.Dx()*b.Dy() >= 245*300 {
+		t.Fatal("expected the embedded image to be cropped to about", x2-x1, "x", y2-y1, "got:", b)
+	}
+	// Size of an image pixel on the page.
+	px := pg.W / 245
+	const eps = 0.01
+	for i, page := range pageContents(t, pdf) {
+		d := imageDraws(page)[0]
+		if math.Abs(d.W-float64(b.Dx())*px) > eps || math.Abs(d.H-float64(b.Dy())*px) > eps {
+			t.Fatal("expected the cropped image at its layout scale on page", i+1, "got:", d)
+		}
+		// Covers the page, give or take the fraction of a pixel the crop is rounded to.
+		for _, v := range [][2]float64{{d.X, d.X + d.W - pg.W}, {d.Y, d.Y + d.H - pg.H}} {
+			if v[0] > eps || v[0] < -px-eps || v[1] < -eps || v[1] > px+eps {
+				t.Fatal("expected the cropped image to cover page", i+1, "got:", d)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package p4p
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// Appearance of page numbers; see SetPageNumbers.
+type PageNumberOptions struct {
+	// fmt format receiving the page number and, if it has a second verb, the page count,
+	// e.g. "%d / %d" or "- %d -" (default: "%d").
+	Format string
+	// Core font family: Helvetica, Times or Courier (default: Helvetica).
+	Font string
+	// Font size in points (default: 10).
+	Size float64
+	// Text color (default: black).
+	Color color.Color
+	// Horizontal position on the page (default: centered).
+	Align HAlign
+	// Print the numbers at the top of the page instead of the bottom.
+	Header bool
+	// Distance of the numbers from the page edges in points (default: 18).
+	Margin float64
+}
+
+// A page to number once the page count is known.
+type numberedPage struct {
+	page int
+	size PageSize
+	opts PageNumberOptions
+}
+
+// Numbers every page added afterwards. The numbers are drawn when the document is written, on top of
+// the page content, and count all pages of the document. Passing nil stops numbering further pages.
+func (g *Generator) SetPageNumbers(opts *PageNumberOptions) {
+	if opts == nil {
+		g.pageNumbers = nil
+		return
+	}
+	o := *opts
+	if o.Format == "" {
+		o.Format = "%d"
+	}
+	if o.Font == "" {
+		o.Font = "Helvetica"
+	}
+	if o.Size <= 0 {
+		o.Size = 10
+	}
+	if o.Color == nil {
+		o.Color = color.Black
+	}
+	if o.Margin <= 0 {
+		o.Margin = 18
+	}
+	g.pageNumbers = &o
+}
+
+// Draws the numbers of all numbered pages not drawn yet.
+func (g *Generator) drawPageNumbers() {
+	if len(g.numberedPages) == 0 {
+		return
+	}
+	if g.tr == nil {
+		// Core fonts only support cp1252.
+		g.tr = g.pdf.UnicodeTranslatorFromDescriptor("")
+	}
+	total := g.pdf.PageCount()
+	last := g.pdf.PageNo()
+	// gofpdf places text relative to the height of the last page added, whichever page it is drawn on.
+	_, lastH := g.pdf.GetPageSize()
+	for _, p := range g.numberedPages {
+		o := p.opts
+		args := []any{p.page, total}
+		// Pass only as many arguments as the format uses, so there is no %!(EXTRA …) suffix.
+		args = args[:min(strings.Count(strings.ReplaceAll(o.Format, "%%", ""), "%"), len(args))]
+		text := fmt.Sprintf(o.Format, args...)
+
+		rect := Rect{X: o.Margin, Y: p.size.H - o.Margin - o.Size, W: p.size.W - 2*o.Margin, H: o.Size}
+		if o.Header {
+			rect.Y = o.Margin
+		}
+		align := "C"
+		switch o.Align {
+		case Left:
+			align = "L"
+		case Right:
+			align = "R"
+		}
+
+		g.pdf.SetPage(p.page)
+		g.pdf.SetFont(o.Font, "", o.Size)
+		// Setting the same fill and text color makes gofpdf emit the color on this page.
+		c := g.color(o.Color)
+		setFillColor(g.pdf, c)
+		r, gr, b, _ := c.RGBA()
+		g.pdf.SetTextColor(int(r>>8), int(gr>>8), int(b>>8))
+		g.pdf.SetXY(rect.X, rect.Y+lastH-p.size.H)
+		g.pdf.CellFormat(rect.W, rect.H, g.tr(text), "", 0, align+"M", false, 0, "")
+	}
+	g.pdf.SetPage(last)
+	g.pdf.SetTextColor(0, 0, 0)
+	g.numberedPages = nil
+}
@@ -0,0 +1,65 @@
+package p4p_test
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Returns the position of the text drawn in a content stream, in PDF coordinates.
+func textPosition(t *testing.T, content, text string) (x, y float64) {
+	t.Helper()
+	m := regexp.MustCompile(`BT ([-\d.]+) ([-\d.]+) Td \(` + regexp.QuoteMeta(text) + `\) ?Tj ET`).FindStringSubmatch(content)
+	if m == nil {
+		t.Fatalf("text %q not found", text)
+	}
+	x, _ = strconv.ParseFloat(m[1], 64)
+	y, _ = strconv.ParseFloat(m[2], 64)
+	return x, y
+}
+
+func TestPageNumbers(t *testing.T) {
+	pg := p4p.A4()
+	g := p4p.NewGenerator(pg)
+	add := func() {
+		if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add()
+	for _, align := range []p4p.HAlign{p4p.Left, p4p.CenterH, p4p.Right} {
+		g.SetPageNumbers(&p4p.PageNumberOptions{Format: "%d / %d", Font: "Courier", Size: 12, Align: align})
+		add()
+	}
+	g.SetPageNumbers(&p4p.PageNumberOptions{Format: "- %d -", Header: true})
+	add()
+	g.SetPageNumbers(nil)
+	add()
+
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 6 {
+		t.Fatal("expected 6 pages, got:", len(pages))
+	}
+	for _, i := range []int{0, 5} {
+		if regexp.MustCompile(`Td \(\d`).MatchString(pages[i]) {
+			t.Fatal("expected no number on page", i+1)
+		}
+	}
+	var xs [3]float64
+	for i := range xs {
+		var y float64
+		xs[i], y = textPosition(t, pages[i+1], strconv.Itoa(i+2)+" / 6")
+		if y > 40 {
+			t.Fatal("expected the number at the bottom of page", i+2, "got:", y)
+		}
+	}
+	if xs[0] >= xs[1] || math.Abs(xs[1]-(xs[0]+xs[2])/2) > 0.01 {
+		t.Fatal("expected the centered number halfway between left and right ones, got:", xs)
+	}
+	if _, y := textPosition(t, pages[4], "- 5 -"); y < pg.H-40 {
+		t.Fatal("expected the number at the top of the page, got:", y)
+	}
+}
@@ -0,0 +1,111 @@
+package p4p
+
+import (
+	"bytes"
+	"image"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Same as AddImage, but all pages added for the image have the given size instead of the
+// generator's, overriding SetPageSizeFromDPI, SetASeriesPageSizes and SetAutoOrient.
+func (g *Generator) AddImageWithPageSize(img image.Image, pageSize PageSize, opts ImageOptions) error {
+	return g.withPageSize(pageSize, func() error {
+		return g.AddImage(img, opts)
+	})
+}
+
+// Same as AddImageFile, but all pages added for the image have the given size instead of the
+// generator's, overriding SetPageSizeFromDPI, SetASeriesPageSizes and SetAutoOrient.
+func (g *Generator) AddImageFileWithPageSize(path string, pageSize PageSize, opts ImageOptions) error {
+	return g.withPageSize(pageSize, func() error {
+		return g.AddImageFile(path, opts)
+	})
+}
+
+// Turns the page of every image added afterwards that gets a page of its own to the image's orientation,
+// e.g. landscape for images wider than tall, by rotating the generator's page size. Square images keep it.
+func (g *Generator) SetAutoOrient(enabled bool) {
+	g.autoOrient = enabled
+}
+
+// Sizes the page of every image added afterwards that gets a page of its own to the smallest A-series size
+// from A6 to A1 holding the image at dpi, turned to the image's orientation, e.g. for documents mixing A4
+// pages with A3 foldouts. Images too large for A1 get an A1 page. Zero or less turns this off. Takes
+// precedence over SetPageSizeFromDPI.
+func (g *Generator) SetASeriesPageSizes(dpi float64) {
+	g.aSeriesDPI = dpi
+}
+
+// Returns the smallest A-series page size holding an image of the given pixel size at dpi, in the image's orientation.
+func aSeriesPageSize(px image.Point, dpi float64) PageSize {
+	short := float64(min(px.X, px.Y)) / dpi * 72
+	long := float64(max(px.X, px.Y)) / dpi * 72
+	size := A1()
+	for _, s := range []PageSize{A6(), A5(), A4(), A3(), A2()} {
+		// Sizes are rounded to hundredths of a point.
+		if short <= s.W+0.01 && long <= s.H+0.01 {
+			size = s
+			break
+		}
+	}
+	if px.X > px.Y {
+		size = size.Rotate()
+	}
+	return size
+}
+
+// Returns the pixel size an image is laid out at, from its encoded data unless it was added from memory.
+func imageSize(data []byte, opts ImageOptions) (image.Point, bool) {
+	switch {
+	case opts.layoutSize != (image.Point{}):
+		return opts.layoutSize, true
+	case opts.decoded != nil:
+		return opts.decoded.Bounds().Size(), true
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	return image.Pt(cfg.Width, cfg.Height), err == nil
+}
+
+// Calls add with the pages it adds sized to pageSize.
+func (g *Generator) withPageSize(pageSize PageSize, add func() error) error {
+	defer func(size PageSize, fromDPI bool, aSeriesDPI float64, autoOrient bool) {
+		g.pageSize, g.pageSizeFromDPI, g.aSeriesDPI, g.autoOrient = size, fromDPI, aSeriesDPI, autoOrient
+	}(g.pageSize, g.pageSizeFromDPI, g.aSeriesDPI, g.autoOrient)
+	g.pageSize = pageSize.Convert(Point)
+	g.pageSizeFromDPI, g.aSeriesDPI, g.autoOrient = false, 0, false
+	return add()
+}
+
+// Returns the page size named by the words in a file name, starting from pageSize; see
+// ImageOptions.FilenameHints. Returns false if the name has no such words.
+func filenamePageSize(path string, pageSize PageSize) (PageSize, bool) {
+	base := filepath.Base(path)
+	words := strings.FieldsFunc(strings.TrimSuffix(base, filepath.Ext(base)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sizes := map[string]func() PageSize{
+		"a1": A1, "a2": A2, "a3": A3, "a4": A4, "a5": A5, "a6": A6,
+		"legal": Legal, "letter": Letter, "tabloid": Tabloid,
+	}
+	found := false
+	var landscape, portrait bool
+	for _, w := range words {
+		w = strings.ToLower(w)
+		switch w {
+		case "landscape":
+			landscape, found = true, true
+		case "portrait":
+			portrait, found = true, true
+		default:
+			if size, ok := sizes[w]; ok {
+				pageSize, found = size(), true
+			}
+		}
+	}
+	if landscape && pageSize.W < pageSize.H || portrait && pageSize.W > pageSize.H {
+		pageSize = pageSize.Rotate()
+	}
+	return pageSize, found
+}
@@ -0,0 +1,140 @@
+package p4p_test
+
+import (
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddImageWithPageSize(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	img := decodeFile(t, "gophers/gopher.png")
+	landscape := p4p.A4().Rotate()
+	if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageWithPageSize(img, landscape, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	// In a different unit.
+	if err := g.AddImageFileWithPageSize("gophers/gopher1.jpg", landscape.Convert(p4p.Millimeter), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageFile("gophers/gopher1.jpg", p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+
+	pdf := writePDF(t, g)
+	boxes := regexp.MustCompile(`/Type /Page\n(?:/Parent 1 0 R\n)?(?:/MediaBox \[0 0 ([\d.]+ [\d.]+)\])?`).FindAllSubmatch(pdf, -1)
+	want := []string{"", "841.89 595.28", "841.89 595.28", ""}
+	if len(boxes) != len(want) {
+		t.Fatal("expected 4 pages, got:", len(boxes))
+	}
+	for i, box := range boxes {
+		if string(box[1]) != want[i] {
+			t.Errorf("page %d: expected MediaBox %q, got %q", i+1, want[i], box[1])
+		}
+	}
+	// Fit to each page's own size.
+	for i, page := range pageContents(t, pdf) {
+		pg := p4p.A4()
+		if i == 1 || i == 2 {
+			pg = landscape
+		}
+		d := imageDraws(page)[0]
+		if d.W > pg.W+0.01 || d.H > pg.H+0.01 || math.Abs(d.W-pg.W) > 0.01 && math.Abs(d.H-pg.H) > 0.01 {
+			t.Errorf("page %d: expected the image fit to %+v, got %+v", i+1, pg, d)
+		}
+	}
+}
+
+func TestFilenameHints(t *testing.T) {
+	data, err := os.ReadFile("gophers/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	g := p4p.NewGenerator(p4p.A4())
+	for _, name := range []string{"gopher_landscape.png", "scan_A5.png", "Letter-Landscape.png", "landscape.png", "a4_portrait.png"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.AddImageFile(path, p4p.ImageOptions{FilenameHints: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Without hints, the name has no effect.
+	if err := g.AddImageFile(filepath.Join(dir, "landscape.png"), p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	boxes := regexp.MustCompile(`/Type /Page\n(?:/Parent 1 0 R\n)?(?:/MediaBox \[0 0 ([\d.]+ [\d.]+)\])?`).FindAllSubmatch(writePDF(t, g), -1)
+	want := []string{"841.89 595.28", "420.94 595.28", "792.00 612.00", "841.89 595.28", "", ""}
+	if len(boxes) != len(want) {
+		t.Fatal("expected", len(want), "pages, got:", len(boxes))
+	}
+	for i, box := range boxes {
+		if string(box[1]) != want[i] {
+			t.Errorf("page %d: expected MediaBox %q, got %q", i+1, want[i], box[1])
+		}
+	}
+}
+
+func TestAutoOrient(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetAutoOrient(true)
+	if err := g.AddImage(image.NewGray(image.Rect(0, 0, 300, 200)), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	// Tall.
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	// Overridden by an explicit page size.
+	if err := g.AddImageWithPageSize(image.NewGray(image.Rect(0, 0, 300, 200)), p4p.A5(), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+
+	pdf := writePDF(t, g)
+	boxes := regexp.MustCompile(`/Type /Page\n(?:/Parent 1 0 R\n)?(?:/MediaBox \[0 0 ([\d.]+ [\d.]+)\])?`).FindAllSubmatch(pdf, -1)
+	want := []string{"841.89 595.28", "", "420.94 595.28"}
+	if len(boxes) != len(want) {
+		t.Fatal("expected", len(want), "pages, got:", len(boxes))
+	}
+	for i, box := range boxes {
+		if string(box[1]) != want[i] {
+			t.Errorf("page %d: expected MediaBox %q, got %q", i+1, want[i], box[1])
+		}
+	}
+	// The landscape image fills the width of its landscape page.
+	if d := imageDraws(pageContents(t, pdf)[0])[0]; math.Abs(d.W-841.89) > 0.01 {
+		t.Error("expected the landscape image to be fitted to the landscape page, got:", d)
+	}
+}
+
+func TestASeriesPageSizes(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetASeriesPageSizes(300)
+	for _, size := range []image.Point{{1500, 2000}, {3000, 4000}, {2000, 1200}, {1000, 1000}} {
+		if err := g.AddImage(image.NewGray(image.Rectangle{Max: size}), p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	boxes := regexp.MustCompile(`/Type /Page\n(?:/Parent 1 0 R\n)?(?:/MediaBox \[0 0 ([\d.]+ [\d.]+)\])?`).FindAllSubmatch(writePDF(t, g), -1)
+	// A5, A3, A5 landscape and A6.
+	want := []string{"420.94 595.28", "841.89 1190.55", "595.28 420.94", "297.64 420.94"}
+	if len(boxes) != len(want) {
+		t.Fatal("expected", len(want), "pages, got:", len(boxes))
+	}
+	for i, box := range boxes {
+		if string(box[1]) != want[i] {
+			t.Errorf("page %d: expected MediaBox %q, got %q", i+1, want[i], box[1])
+		}
+	}
+}
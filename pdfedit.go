@@ -0,0 +1,415 @@
+package p4p
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// A PDF as written by gofpdf, split into its indirect objects so that entries gofpdf has no API for
+// can be added before the file is reassembled with a new cross-reference table. Relies on the
+// plain structure gofpdf always produces: a header, uncompressed objects, a classic cross-reference
+// table and a trailer, or the one writeCompact produces from it.
+type pdfFile struct {
+	header []byte
+	// Object contents without the "n 0 obj" and "endobj" lines; index 0 is unused.
+	objs [][]byte
+	// Trailer entries other than /Size.
+	trailer []byte
+	// Number of the file's first page in the generator's document, which differs from 1 when the
+	// document is split into several files.
+	firstPage int
+}
+
+var (
+	startXrefRe = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n?$`)
+	sizeRe      = regexp.MustCompile(`/Size \d+\n`)
+	rootRe      = regexp.MustCompile(`/Root (\d+) 0 R`)
+	kidsRe      = regexp.MustCompile(`/Kids \[([^\]]*)\]`)
+	refRe       = regexp.MustCompile(`(\d+) 0 R`)
+)
+
+var errMalformedPDF = errors.New("p4p: unexpected PDF structure")
+
+func parsePDF(data []byte) (*pdfFile, error) {
+	m := startXrefRe.FindSubmatch(data)
+	if m == nil {
+		return nil, errMalformedPDF
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if xref >= len(data) {
+		return nil, errMalformedPDF
+	}
+
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		return parseCompactPDF(data, xref)
+	}
+
+	// Cross-reference table.
+	lines := strings.Split(string(data[xref:]), "\n")
+	if len(lines) < 3 || lines[0] != "xref" {
+		return nil, errMalformedPDF
+	}
+	var first, count int
+	if _, err := fmt.Sscanf(lines[1], "%d %d", &first, &count); err != nil || first != 0 || len(lines) < 2+count+1 {
+		return nil, errMalformedPDF
+	}
+	offsets := make([]int, count)
+	for n := 1; n < count; n++ {
+		off, err := strconv.Atoi(strings.Fields(lines[2+n])[0])
+		if err != nil || off >= xref {
+			return nil, errMalformedPDF
+		}
+		offsets[n] = off
+	}
+
+	// Trailer.
+	trailer := strings.Join(lines[2+count:], "\n")
+	start, end := strings.Index(trailer, "<<\n"), strings.LastIndex(trailer, ">>\nstartxref")
+	if !strings.HasPrefix(trailer, "trailer\n") || start < 0 || end < start {
+		return nil, errMalformedPDF
+	}
+
+	p := &pdfFile{
+		objs:      make([][]byte, count),
+		trailer:   sizeRe.ReplaceAll([]byte(trailer[start+3:end]), nil),
+		firstPage: 1,
+	}
+	p.header = data[:xref]
+	for n := 1; n < count; n++ {
+		// Objects end where the next one (in file order) or the cross-reference table begins.
+		next := xref
+		for _, off := range offsets[1:] {
+			if off > offsets[n] && off < next {
+				next = off
+			}
+		}
+		// Objects imported with gofpdi are followed by a blank line.
+		obj := bytes.TrimRight(data[offsets[n]:next], "\n")
+		head := []byte(strconv.Itoa(n) + " 0 obj\n")
+		if !bytes.HasPrefix(obj, head) || !bytes.HasSuffix(obj, []byte("\nendobj")) {
+			return nil, errMalformedPDF
+		}
+		p.objs[n] = obj[len(head) : len(obj)-len("endobj")]
+		if offsets[n] < len(p.header) {
+			p.header = data[:offsets[n]]
+		}
+	}
+	return p, nil
+}
+
+var (
+	xrefStreamRe = regexp.MustCompile(`^(\d+) 0 obj\n<<\n/Type /XRef\n/Size (\d+)\n/W \[1 4 2\]\n`)
+	objStmRe     = regexp.MustCompile(`^<</Type /ObjStm /N (\d+) /First (\d+) `)
+)
+
+// Parses a file written by writeCompact, with a cross-reference stream at offset xref and objects
+// packed into object streams.
+func parseCompactPDF(data []byte, xref int) (*pdfFile, error) {
+	m := xrefStreamRe.FindSubmatch(data[xref:])
+	if m == nil {
+		return nil, errMalformedPDF
+	}
+	// The object up to the end of the file, with the trailer entries between the ones writeCompact adds.
+	xrefObj := data[xref+len(m[1])+len(" 0 obj\n"):]
+	dict, _ := splitStream(xrefObj)
+	start, end := len(m[0])-len(m[1])-len(" 0 obj\n"), bytes.LastIndex(dict, []byte("/Filter /FlateDecode\n"))
+	entries, err := streamData(xrefObj)
+	count := atoi(m[2])
+	if end < start || err != nil || len(entries) != 7*count {
+		return nil, errMalformedPDF
+	}
+
+	p := &pdfFile{
+		objs:      make([][]byte, count),
+		trailer:   dict[start:end],
+		header:    data[:xref],
+		firstPage: 1,
+	}
+	// Offsets of the objects written as they are, and of the cross-reference stream, which follows them.
+	offsets := []int{xref}
+	for n := 1; n < count; n++ {
+		if e := entries[7*n:]; e[0] == 1 {
+			offsets = append(offsets, int(binary.BigEndian.Uint32(e[1:])))
+		}
+	}
+	for n := 1; n < count; n++ {
+		e := entries[7*n:]
+		if e[0] != 1 {
+			continue
+		}
+		off := int(binary.BigEndian.Uint32(e[1:]))
+		if off == xref {
+			// The cross-reference stream itself.
+			continue
+		}
+		next := xref
+		for _, o := range offsets {
+			if o > off && o < next {
+				next = o
+			}
+		}
+		if off >= next {
+			return nil, errMalformedPDF
+		}
+		obj := data[off:next]
+		head := []byte(strconv.Itoa(n) + " 0 obj\n")
+		if !bytes.HasPrefix(obj, head) || !bytes.HasSuffix(obj, []byte("\nendobj\n")) {
+			return nil, errMalformedPDF
+		}
+		p.objs[n] = obj[len(head) : len(obj)-len("endobj\n")]
+		if off < len(p.header) {
+			p.header = data[:off]
+		}
+	}
+	// Objects packed into object streams.
+	for n := 1; n < count; n++ {
+		e := entries[7*n:]
+		if e[0] != 2 {
+			continue
+		}
+		stm, i := int(binary.BigEndian.Uint32(e[1:])), int(binary.BigEndian.Uint16(e[5:]))
+		obj, err := p.packedObj(stm, i)
+		if err != nil {
+			return nil, err
+		}
+		p.objs[n] = obj
+	}
+	return p, nil
+}
+
+// Returns the object at index i of object stream stm.
+func (p *pdfFile) packedObj(stm, i int) ([]byte, error) {
+	obj := p.obj(stm)
+	m := objStmRe.FindSubmatch(obj)
+	if m == nil {
+		return nil, errMalformedPDF
+	}
+	data, err := streamData(obj)
+	first := atoi(m[2])
+	if err != nil || first > len(data) {
+		return nil, errMalformedPDF
+	}
+	// Pairs of object numbers and offsets.
+	index := strings.Fields(string(data[:first]))
+	if 2*i+1 >= len(index) || len(index) != 2*atoi(m[1]) {
+		return nil, errMalformedPDF
+	}
+	start, end := first+atoi([]byte(index[2*i+1])), len(data)
+	if 2*i+3 < len(index) {
+		end = first + atoi([]byte(index[2*i+3]))
+	}
+	if start > end || end > len(data) {
+		return nil, errMalformedPDF
+	}
+	return data[start:end], nil
+}
+
+// Returns the object number of the document catalog.
+func (p *pdfFile) root() int {
+	m := rootRe.FindSubmatch(p.trailer)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	return n
+}
+
+// Returns the object numbers of all pages in page order.
+func (p *pdfFile) pages() []int {
+	// gofpdf always writes the page tree root as object 1.
+	m := kidsRe.FindSubmatch(p.objs[1])
+	if m == nil {
+		return nil
+	}
+	var pages []int
+	for _, ref := range refRe.FindAllSubmatch(m[1], -1) {
+		n, _ := strconv.Atoi(string(ref[1]))
+		pages = append(pages, n)
+	}
+	return pages
+}
+
+// Appends a new object and returns its object number.
+func (p *pdfFile) add(obj string) int {
+	p.objs = append(p.objs, []byte(obj+"\n"))
+	return len(p.objs) - 1
+}
+
+// Replaces the contents of an existing object.
+func (p *pdfFile) set(n int, obj string) {
+	p.objs[n] = []byte(obj + "\n")
+}
+
+// Appends entries to the end of the top-level dictionary of object n.
+func (p *pdfFile) extendDict(n int, entries string) {
+	obj := p.objs[n]
+	dict := obj
+	if i := bytes.Index(obj, []byte("\nstream\n")); i >= 0 {
+		dict = obj[:i]
+	}
+	i := bytes.LastIndex(dict, []byte(">>"))
+	if i < 0 {
+		return
+	}
+	var b bytes.Buffer
+	b.Write(obj[:i])
+	b.WriteString("\n" + entries + "\n")
+	b.Write(obj[i:])
+	p.objs[n] = b.Bytes()
+}
+
+func (p *pdfFile) write(w io.Writer) error {
+	var b bytes.Buffer
+	b.Write(p.header)
+	offsets := make([]int, len(p.objs))
+	for n := 1; n < len(p.objs); n++ {
+		offsets[n] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", n)
+		b.Write(p.objs[n])
+		b.WriteString("endobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(p.objs))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<<\n/Size %d\n", len(p.objs))
+	b.Write(p.trailer)
+	fmt.Fprintf(&b, ">>\nstartxref\n%d\n%%%%EOF\n", xref)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+var versionRe = regexp.MustCompile(`^%PDF-1\.[0-4]\b`)
+
+// Maximum number of objects packed into a single object stream.
+const objStmSize = 100
+
+// Writes the file using a cross-reference stream, packing all objects that are not streams themselves
+// into object streams (PDF 1.5).
+func (p *pdfFile) writeCompact(w io.Writer) error {
+	var b bytes.Buffer
+	b.Write(versionRe.ReplaceAll(p.header, []byte("%PDF-1.5")))
+
+	// Cross-reference entries as type, field 2, field 3.
+	type xrefEntry struct {
+		typ byte
+		f2  uint32
+		f3  uint16
+	}
+	xref := make([]xrefEntry, len(p.objs))
+	xref[0] = xrefEntry{0, 0, 0xffff}
+	putObj := func(n int, obj []byte) {
+		xref[n] = xrefEntry{1, uint32(b.Len()), 0}
+		fmt.Fprintf(&b, "%d 0 obj\n", n)
+		b.Write(obj)
+		b.WriteString("endobj\n")
+	}
+
+	// Strings in encrypted files are encrypted with the number of the object they are in, so objects must stay put.
+	pack := !bytes.Contains(p.trailer, []byte("/Encrypt"))
+	var packed []int
+	for n := 1; n < len(p.objs); n++ {
+		if pack && !bytes.Contains(p.objs[n], []byte("\nstream\n")) {
+			packed = append(packed, n)
+		} else {
+			putObj(n, p.objs[n])
+		}
+	}
+	next := len(p.objs)
+	for len(packed) > 0 {
+		chunk := packed[:min(len(packed), objStmSize)]
+		packed = packed[len(chunk):]
+		stm := next
+		next++
+		xref = append(xref, xrefEntry{})
+		var index, body bytes.Buffer
+		for i, n := range chunk {
+			fmt.Fprintf(&index, "%d %d ", n, body.Len())
+			body.Write(p.objs[n])
+			xref[n] = xrefEntry{2, uint32(stm), uint16(i)}
+		}
+		data := compress(append(index.Bytes(), body.Bytes()...))
+		putObj(stm, []byte(fmt.Sprintf("<</Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d>>\nstream\n%s\nendstream\n",
+			len(chunk), index.Len(), len(data), data)))
+	}
+
+	// The cross-reference stream itself.
+	xrefObj := next
+	xref = append(xref, xrefEntry{1, uint32(b.Len()), 0})
+	var entries bytes.Buffer
+	for _, e := range xref {
+		entries.WriteByte(e.typ)
+		binary.Write(&entries, binary.BigEndian, e.f2)
+		binary.Write(&entries, binary.BigEndian, e.f3)
+	}
+	data := compress(entries.Bytes())
+	start := b.Len()
+	fmt.Fprintf(&b, "%d 0 obj\n<<\n/Type /XRef\n/Size %d\n/W [1 4 2]\n", xrefObj, len(xref))
+	b.Write(p.trailer)
+	fmt.Fprintf(&b, "/Filter /FlateDecode\n/Length %d\n>>\nstream\n%s\nendstream\nendobj\n", len(data), data)
+	fmt.Fprintf(&b, "startxref\n%d\n%%%%EOF\n", start)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func compress(data []byte) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
+
+// Returns s as a PDF text string, UTF-16 encoded if it is not plain ASCII.
+func pdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`).Replace(s) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", c)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// Reports whether the output needs to be amended after gofpdf has written it.
+func (g *Generator) amendsOutput() bool {
+	return g.lang != "" || len(g.figures) > 0 || g.pageProgression != "" || g.objectStreams ||
+		len(g.geoLocations) > 0 || g.tumbleDuplex ||
+		len(g.interpolate) > 0 || len(g.cmyk) > 0 || !g.created.IsZero() || !g.modified.IsZero() ||
+		g.mirrorOutput || len(g.notes) > 0 || g.fingerprint || g.protection != nil
+}
+
+// Adds everything to the output that gofpdf cannot write itself.
+func (g *Generator) amend(p *pdfFile) {
+	g.putLanguage(p)
+	g.putDates(p)
+	g.putStructTree(p)
+	g.putViewerPreferences(p)
+	g.putGeoLocations(p)
+	g.putTumbleDuplex(p)
+	g.putCMYK(p)
+	g.putInterpolate(p)
+	g.putMirror(p)
+	g.putNotes(p)
+	g.putFingerprint(p)
+	g.encrypt(p)
+}
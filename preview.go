@@ -0,0 +1,44 @@
+package p4p
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+)
+
+// Writes an SVG of the page with the given 1-based number, sized in points, showing the page and the
+// placement of every image on it, for previewing layouts in a browser. Images are referenced by the path
+// or URL they were read from, and drawn as gray rectangles if they were added from memory. Cropped images
+// are clipped to their visible part.
+func (g *Generator) WriteSVGPreview(w io.Writer, page int) error {
+	if page < 1 || page > g.pdf.PageNo() {
+		return fmt.Errorf("p4p: no page %d", page)
+	}
+	pgW, pgH, _ := g.pdf.PageSize(page)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" width=\"%.2fpt\" height=\"%.2fpt\" viewBox=\"0 0 %.2f %.2f\">\n",
+		pgW, pgH, pgW, pgH)
+	fmt.Fprintf(bw, "<rect x=\"0\" y=\"0\" width=\"%.2f\" height=\"%.2f\" fill=\"white\" stroke=\"gray\"/>\n", pgW, pgH)
+	for i, e := range g.index {
+		if e.Page != page {
+			continue
+		}
+		r := e.Rect
+		clip := ""
+		if e.Crop != nil && e.size.X > 0 && e.size.Y > 0 {
+			sx, sy := r.W/float64(e.size.X), r.H/float64(e.size.Y)
+			fmt.Fprintf(bw, "<clipPath id=\"crop%d\"><rect x=\"%.2f\" y=\"%.2f\" width=\"%.2f\" height=\"%.2f\"/></clipPath>\n",
+				i, r.X+float64(e.Crop.Min.X)*sx, r.Y+float64(e.Crop.Min.Y)*sy, float64(e.Crop.Dx())*sx, float64(e.Crop.Dy())*sy)
+			clip = fmt.Sprintf(" clip-path=\"url(#crop%d)\"", i)
+		}
+		if e.Source == "" {
+			fmt.Fprintf(bw, "<rect x=\"%.2f\" y=\"%.2f\" width=\"%.2f\" height=\"%.2f\" fill=\"lightgray\"%s/>\n", r.X, r.Y, r.W, r.H, clip)
+			continue
+		}
+		fmt.Fprintf(bw, "<image x=\"%.2f\" y=\"%.2f\" width=\"%.2f\" height=\"%.2f\" preserveAspectRatio=\"none\" xlink:href=\"%s\"%s/>\n",
+			r.X, r.Y, r.W, r.H, html.EscapeString(e.Source), clip)
+	}
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
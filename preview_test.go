@@ -0,0 +1,85 @@
+package p4p_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math"
+	"strconv"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestWriteSVGPreview(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	opts := p4p.ImageOptions{Mode: p4p.Fit, Margins: p4p.Margins{Top: 36, Bottom: 36, Left: 36, Right: 36}}
+	if err := g.AddImageFile("gophers/gopher.png", opts); err != nil {
+		t.Fatal(err)
+	}
+	file, img := decodeFile(t, "gophers/gopher.png"), decodeFile(t, "gophers/gopher1.jpg")
+	if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fill}); err != nil {
+		t.Fatal(err)
+	}
+
+	type rect struct {
+		X      float64 `xml:"x,attr"`
+		Y      float64 `xml:"y,attr"`
+		Width  float64 `xml:"width,attr"`
+		Height float64 `xml:"height,attr"`
+		Href   string  `xml:"href,attr"`
+	}
+	var svg struct {
+		Width    string `xml:"width,attr"`
+		Height   string `xml:"height,attr"`
+		ViewBox  string `xml:"viewBox,attr"`
+		Images   []rect `xml:"image"`
+		Rects    []rect `xml:"rect"`
+		ClipPath []rect `xml:"clipPath>rect"`
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+	pt := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+
+	for page := 1; page <= 2; page++ {
+		var out bytes.Buffer
+		if err := g.WriteSVGPreview(&out, page); err != nil {
+			t.Fatal(err)
+		}
+		svg.Images, svg.Rects, svg.ClipPath = nil, nil, nil
+		if err := xml.Unmarshal(out.Bytes(), &svg); err != nil {
+			t.Fatal(err, out.String())
+		}
+		pgW, pgH := p4p.A4().W, p4p.A4().H
+		if svg.Width != pt(pgW)+"pt" || svg.Height != pt(pgH)+"pt" || svg.ViewBox != "0 0 "+pt(pgW)+" "+pt(pgH) {
+			t.Fatal("expected an A4 page, got:", svg.Width, svg.Height, svg.ViewBox)
+		}
+
+		l := p4p.RenderLayoutInRect(p4p.Rect{W: pgW, H: pgH}, p4p.Point, file.Bounds().Dx(), file.Bounds().Dy(), opts)
+		got := rect{}
+		if page == 1 {
+			if len(svg.Images) != 1 || svg.Images[0].Href != "gophers/gopher.png" {
+				t.Fatalf("expected the image file on page 1, got: %+v", svg.Images)
+			}
+			got = svg.Images[0]
+		} else {
+			// Images added from memory have no source to refer to.
+			if len(svg.Images) != 0 || len(svg.Rects) != 2 || len(svg.ClipPath) != 1 {
+				t.Fatalf("expected a clipped placeholder on page 2, got: %s", out.String())
+			}
+			l = p4p.RenderLayoutInRect(p4p.Rect{W: pgW, H: pgH}, p4p.Point, img.Bounds().Dx(), img.Bounds().Dy(), p4p.ImageOptions{Mode: p4p.Fill})
+			got = svg.Rects[1]
+			// The visible part is rounded to whole pixels at the page edges.
+			px := l.W / float64(img.Bounds().Dx())
+			c := svg.ClipPath[0]
+			if c.X > 0.01 || c.X < -px || c.X+c.Width < pgW-px || c.X+c.Width > pgW+px || !near(c.Y, 0) || !near(c.Height, pgH) {
+				t.Fatalf("expected the placeholder to be clipped to the page, got: %+v", c)
+			}
+		}
+		if !near(got.X, l.X) || !near(got.Y, l.Y) || !near(got.Width, l.W) || !near(got.Height, l.H) {
+			t.Fatalf("page %d: expected the image at %+v, got: %+v", page, l, got)
+		}
+	}
+
+	if err := g.WriteSVGPreview(&bytes.Buffer{}, 3); err == nil {
+		t.Fatal("expected an error for a page that does not exist")
+	}
+}
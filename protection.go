@@ -0,0 +1,214 @@
+package p4p
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// What viewers let users do with a protected document; see SetProtection.
+type Permission byte
+
+const (
+	AllowPrint    Permission = gofpdf.CnProtectPrint
+	AllowCopy     Permission = gofpdf.CnProtectCopy
+	AllowModify   Permission = gofpdf.CnProtectModify
+	AllowAnnotate Permission = gofpdf.CnProtectAnnotForms
+)
+
+type protection struct {
+	userPass, ownerPass []byte
+	perms               Permission
+}
+
+// Encrypts the document, asking viewers for userPass to open it and granting only perms to those who do
+// not know the owner password ownerPass; an empty ownerPass is replaced by a random one. Must be called
+// before the first image is added. Documents are encrypted with 40-bit RC4, which keeps out casual readers
+// only, and viewers may ignore the permissions.
+func (g *Generator) SetProtection(userPass, ownerPass string, perms ...Permission) error {
+	if g.pdf.PageNo() > 0 {
+		return errors.New("p4p: SetProtection must be called before adding images")
+	}
+	prot := &protection{userPass: []byte(userPass), ownerPass: []byte(ownerPass)}
+	if ownerPass == "" {
+		prot.ownerPass = make([]byte, 16)
+		rand.Read(prot.ownerPass)
+	}
+	for _, p := range perms {
+		prot.perms |= p
+	}
+	g.protection = prot
+	return nil
+}
+
+// Padding of passwords to 32 bytes, as defined by the standard security handler.
+var passwordPadding = []byte{
+	0x28, 0xbf, 0x4e, 0x5e, 0x4e, 0x75, 0x8a, 0x41, 0x64, 0x00, 0x4e, 0x56, 0xff, 0xfa, 0x01, 0x08,
+	0x2e, 0x2e, 0x00, 0xb6, 0xd0, 0x68, 0x3e, 0x80, 0x2f, 0x0c, 0xa9, 0xfe, 0x64, 0x53, 0x69, 0x7a,
+}
+
+func padPassword(pass []byte) []byte {
+	return append(append([]byte{}, pass...), passwordPadding...)[:32]
+}
+
+func rc4Crypt(key, data []byte) []byte {
+	c, _ := rc4.NewCipher(key)
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+	return out
+}
+
+// Encrypts all strings and streams of the file with the standard security handler, revision 2, which
+// gofpdf implements too but encrypts all strings of an object after the first one with the wrong key.
+// Must be the last change to the file.
+func (g *Generator) encrypt(p *pdfFile) {
+	prot := g.protection
+	if prot == nil {
+		return
+	}
+	// The file identifier both encryption and the trailer need.
+	id := md5.New()
+	for _, obj := range p.objs {
+		id.Write(obj)
+	}
+	fileID := id.Sum(nil)
+
+	userPass := padPassword(prot.userPass)
+	ownerKey := md5.Sum(padPassword(prot.ownerPass))
+	o := rc4Crypt(ownerKey[:5], userPass)
+	// Bits 7 and 8 and all bits above the permissions must be set.
+	perms := int32(uint32(0xffffffc0) | uint32(prot.perms))
+	var buf bytes.Buffer
+	buf.Write(userPass)
+	buf.Write(o)
+	binary.Write(&buf, binary.LittleEndian, perms)
+	buf.Write(fileID)
+	sum := md5.Sum(buf.Bytes())
+	key := sum[:5]
+	u := rc4Crypt(key, passwordPadding)
+
+	for n := 1; n < len(p.objs); n++ {
+		objKey := md5.Sum(append(append([]byte{}, key...), byte(n), byte(n>>8), byte(n>>16), 0, 0))
+		p.objs[n] = encryptObject(p.objs[n], objKey[:10])
+	}
+	enc := p.add(fmt.Sprintf("<<\n/Filter /Standard\n/V 1\n/R 2\n/O <%x>\n/U <%x>\n/P %d\n>>", o, u, perms))
+	p.trailer = append(p.trailer, fmt.Sprintf("/Encrypt %s\n/ID [<%x> <%x>]\n", ref(enc), fileID, fileID)...)
+}
+
+// Returns the object with all strings in its dictionary, written as hex strings, and its stream encrypted
+// with key.
+func encryptObject(obj, key []byte) []byte {
+	dict, stream := splitStream(obj)
+	var b bytes.Buffer
+	for i := 0; i < len(dict); {
+		switch {
+		case dict[i] == '(':
+			s, end := literalString(dict, i)
+			b.WriteString("<" + hex.EncodeToString(rc4Crypt(key, s)) + ">")
+			i = end
+		case bytes.HasPrefix(dict[i:], []byte("<<")):
+			b.WriteString("<<")
+			i += 2
+		case dict[i] == '<':
+			end := bytes.IndexByte(dict[i:], '>')
+			if end < 0 {
+				end = len(dict) - i - 1
+			}
+			s, _ := hex.DecodeString(string(hexDigits(dict[i+1 : i+end])))
+			b.WriteString("<" + hex.EncodeToString(rc4Crypt(key, s)) + ">")
+			i += end + 1
+		default:
+			b.WriteByte(dict[i])
+			i++
+		}
+	}
+	if stream != nil {
+		end := bytes.LastIndex(stream, []byte("\nendstream"))
+		start := len("\nstream\n")
+		if end >= start {
+			b.Write(stream[:start])
+			b.Write(rc4Crypt(key, stream[start:end]))
+			b.Write(stream[end:])
+		} else {
+			b.Write(stream)
+		}
+	}
+	return b.Bytes()
+}
+
+// Returns the bytes of the literal string starting at data[start], and the index following it.
+func literalString(data []byte, start int) ([]byte, int) {
+	var s []byte
+	depth := 0
+	for i := start; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b':
+				s = append(s, '\b')
+			case 'f':
+				s = append(s, '\f')
+			case '\r', '\n':
+				// Line continuation.
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					for j := 0; j < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; j++ {
+						v = v*8 + int(data[i]-'0')
+						i++
+					}
+					i--
+					s = append(s, byte(v))
+				} else {
+					s = append(s, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				s = append(s, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return s, i + 1
+			}
+			s = append(s, c)
+		default:
+			s = append(s, c)
+		}
+	}
+	return s, len(data)
+}
+
+// Returns the hex digits of a hex string without the white space between them, padded to an even number.
+func hexDigits(s []byte) []byte {
+	var d []byte
+	for _, c := range s {
+		if c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' {
+			d = append(d, c)
+		}
+	}
+	if len(d)%2 != 0 {
+		d = append(d, '0')
+	}
+	return d
+}
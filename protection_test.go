@@ -0,0 +1,104 @@
+package p4p_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/binary"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Returns the key of the standard security handler, revision 2, for the password, or nil if the password
+// is wrong.
+func encryptionKey(t *testing.T, pdf []byte, password string) []byte {
+	t.Helper()
+	m := regexp.MustCompile(`/Filter /Standard\n/V 1\n/R 2\n/O <([0-9a-f]+)>\n/U <([0-9a-f]+)>\n/P (-?\d+)`).FindSubmatch(pdf)
+	id := regexp.MustCompile(`/ID \[<([0-9a-f]+)>`).FindSubmatch(pdf)
+	if m == nil || id == nil {
+		t.Fatal("missing encryption dictionary or file identifier")
+	}
+	o, _ := hex.DecodeString(string(m[1]))
+	u, _ := hex.DecodeString(string(m[2]))
+	perms, _ := strconv.Atoi(string(m[3]))
+	fileID, _ := hex.DecodeString(string(id[1]))
+	padding := []byte("\x28\xbf\x4e\x5e\x4e\x75\x8a\x41\x64\x00\x4e\x56\xff\xfa\x01\x08\x2e\x2e\x00\xb6\xd0\x68\x3e\x80\x2f\x0c\xa9\xfe\x64\x53\x69\x7a")
+
+	var b bytes.Buffer
+	b.Write(append([]byte(password), padding...)[:32])
+	b.Write(o)
+	binary.Write(&b, binary.LittleEndian, int32(perms))
+	b.Write(fileID)
+	sum := md5.Sum(b.Bytes())
+	key := sum[:5]
+	if !bytes.Equal(rc4Crypt(key, padding), u) {
+		return nil
+	}
+	return key
+}
+
+func rc4Crypt(key, data []byte) []byte {
+	c, _ := rc4.NewCipher(key)
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+	return out
+}
+
+func TestSetProtection(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.SetProtection("user", "owner", p4p.AllowPrint, p4p.AllowCopy); err != nil {
+		t.Fatal(err)
+	}
+	g.SetMetadata(p4p.Metadata{Title: "Invoice (March)", Author: "Gopher"})
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{AltText: "A gopher"}); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	if !regexp.MustCompile(`trailer\n<<[^>]*/Encrypt \d+ 0 R`).Match(pdf) {
+		t.Fatal("expected the trailer to refer to an encryption dictionary")
+	}
+	// Printing (4) and copying (16) allowed, with the bits PDF requires to be set.
+	if !bytes.Contains(pdf, []byte("/P -44\n")) {
+		t.Fatal("expected permissions allowing printing and copying")
+	}
+	if bytes.Contains(pdf, []byte("Invoice")) || bytes.Contains(pdf, []byte("A gopher")) {
+		t.Fatal("expected all strings to be encrypted")
+	}
+
+	if encryptionKey(t, pdf, "wrong") != nil {
+		t.Fatal("expected the wrong password to be rejected")
+	}
+	key := encryptionKey(t, pdf, "user")
+	if key == nil {
+		t.Fatal("expected the user password to be accepted")
+	}
+	info := regexp.MustCompile(`/Info (\d+) 0 R`).FindSubmatch(pdf)
+	if info == nil {
+		t.Fatal("missing info dictionary")
+	}
+	n, _ := strconv.Atoi(string(info[1]))
+	dict := regexp.MustCompile(`(?s)\n` + string(info[1]) + ` 0 obj\n(.*?)\nendobj`).FindSubmatch(pdf)
+	if dict == nil {
+		t.Fatal("missing info dictionary")
+	}
+	objKey := md5.Sum(append(append([]byte{}, key...), byte(n), byte(n>>8), byte(n>>16), 0, 0))
+	// Every string of an object is encrypted on its own.
+	for entry, want := range map[string]string{"Title": "Invoice (March)", "Author": "Gopher"} {
+		m := regexp.MustCompile(`/` + entry + ` <([0-9a-f]*)>`).FindSubmatch(dict[1])
+		if m == nil {
+			t.Fatal("missing", entry, "in info dictionary")
+		}
+		s, _ := hex.DecodeString(string(m[1]))
+		if got := string(rc4Crypt(objKey[:10], s)); got != want {
+			t.Fatalf("expected %s %q, got: %q", entry, want, got)
+		}
+	}
+
+	if err := g.SetProtection("user", ""); err == nil {
+		t.Fatal("expected an error setting protection after adding images")
+	}
+}
@@ -0,0 +1,57 @@
+package p4p
+
+import (
+	"image"
+	"math"
+)
+
+// Adds the image as a new page in two resolutions on separate optional content layers: a preview
+// downscaled by previewScale (e.g. 0.25), which is visible by default, and the full resolution image
+// on top of it, which can be turned on in the viewer's layer panel when zooming in. opts.Layer is ignored.
+func (g *Generator) AddImageWithPreview(img image.Image, previewScale float64, opts ImageOptions) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if isNilImage(img) {
+		return ErrNilImage
+	}
+	if previewScale <= 0 || previewScale >= 1 {
+		previewScale = 0.25
+	}
+	b := img.Bounds()
+	preview := resize(img, max(int(math.Round(float64(b.Dx())*previewScale)), 1), max(int(math.Round(float64(b.Dy())*previewScale)), 1))
+
+	if g.previewLayer == 0 {
+		g.previewLayer = g.addLayer("Preview", true)
+		g.fullResLayer = g.addLayer("Full resolution", false)
+	}
+
+	previewTyp, previewData, err := encodeImage(preview, opts)
+	if err != nil {
+		return err
+	}
+	typ, data, err := encodeImage(img, opts)
+	if err != nil {
+		return err
+	}
+	opts = g.nextPageOptions(opts)
+	// Laid out at the same place as the full resolution image, which is captioned and tagged.
+	previewOpts := opts
+	previewOpts.layoutSize = b.Size()
+	previewOpts.Caption, previewOpts.AltText = "", ""
+	previewOpts.Layer = g.previewLayer
+	opts.Layer = g.fullResLayer
+
+	// Both registered first, so that images failing to load don't leave an empty page behind.
+	previewImg, err := g.registerImage(previewTyp, previewData, g.pageRect(), previewOpts)
+	if err != nil {
+		return err
+	}
+	fullImg, err := g.registerImage(typ, data, g.pageRect(), opts)
+	if err != nil {
+		return err
+	}
+	g.addPage()
+	g.drawImage(previewImg)
+	g.drawImage(fullImg)
+	return nil
+}
@@ -0,0 +1,68 @@
+package p4p_test
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddImageWithPreview(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	img := decodeFile(t, "gophers/gopher.png")
+	for _, mode := range []p4p.Mode{p4p.Center, p4p.Fit} {
+		if err := g.AddImageWithPreview(img, 0.25, p4p.ImageOptions{Mode: mode}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := writePDF(t, g)
+	if n := bytes.Count(pdf, []byte("/Type /OCG")); n != 2 {
+		t.Fatal("expected 2 optional content groups, got:", n)
+	}
+	if !bytes.Contains(pdf, []byte("/OCProperties")) {
+		t.Fatal("missing optional content properties")
+	}
+	for i, page := range pageContents(t, pdf) {
+		if !strings.Contains(page, "/OC /OC0 BDC") || !strings.Contains(page, "/OC /OC1 BDC") {
+			t.Fatal("images on page", i+1, "not assigned to layers")
+		}
+		draws := imageDraws(page)
+		if len(draws) != 2 {
+			t.Fatal("expected preview and full resolution image on page", i+1)
+		}
+		preview, full := draws[0], draws[1]
+		if preview != full {
+			t.Fatal("preview not placed like the full resolution image on page", i+1, "got:", preview, full)
+		}
+	}
+}
+
+func TestAddImageWithPreviewPageOptions(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetCoverOptions(&p4p.ImageOptions{Mode: p4p.Fill})
+	// Embedded as a 16-bit PNG, which gofpdf cannot read; no page is added for it.
+	if err := g.AddImageWithPreview(image.NewNRGBA64(image.Rect(0, 0, 40, 40)), 0.25, p4p.ImageOptions{}); err == nil {
+		t.Fatal("expected an error for the image gofpdf cannot read")
+	}
+	// Wide enough to fit the page width.
+	img := image.NewGray(image.Rect(0, 0, 1000, 100))
+	for i := 0; i < 2; i++ {
+		if err := g.AddImageWithPreview(img, 0.25, p4p.ImageOptions{Mode: p4p.Fit, Gutter: 36}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 pages, got:", len(pages))
+	}
+	// The cover fills the first page, the second page has the gutter on the left.
+	if d := imageDraws(pages[0]); len(d) != 2 || math.Abs(d[1].H-p4p.A4().H) > 0.01 {
+		t.Fatal("expected the cover options on page 1, got:", d)
+	}
+	if d := imageDraws(pages[1]); len(d) != 2 || math.Abs(d[0].X-36) > 0.01 || math.Abs(d[1].X-36) > 0.01 {
+		t.Fatal("expected the gutter on page 2, got:", d)
+	}
+}
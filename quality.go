@@ -0,0 +1,94 @@
+package p4p
+
+import (
+	"image"
+	"math"
+)
+
+// Preset trading file size for image quality; see SetQuality.
+type Quality int
+
+const (
+	// Images are embedded at their full resolution, images added from memory as JPEG of quality 75
+	// unless they have transparency.
+	DefaultQuality Quality = iota
+	// For screens: images are downsampled to 150 DPI at the size they are placed at, and encoded as
+	// JPEG of quality 60.
+	WebQuality
+	// For printing: images are downsampled to 300 DPI, and encoded as JPEG of quality 85.
+	PrintQuality
+	// For keeping: images are embedded at their full resolution, images added from memory losslessly
+	// as PNG, and images re-encoded for other reasons as JPEG of quality 95.
+	ArchiveQuality
+)
+
+// Sets SetMaxDPI and the JPEG quality and encoding of images added afterwards according to the preset.
+// ImageOptions.JPEGQuality, AssumeOpaque and AssumeAlpha set on an image take precedence.
+func (g *Generator) SetQuality(q Quality) {
+	g.jpegQuality, g.lossless = 0, false
+	switch q {
+	case DefaultQuality:
+		g.SetMaxDPI(0)
+	case WebQuality:
+		g.SetMaxDPI(150)
+		g.jpegQuality = 60
+	case PrintQuality:
+		g.SetMaxDPI(300)
+		g.jpegQuality = 85
+	case ArchiveQuality:
+		g.SetMaxDPI(0)
+		g.jpegQuality = 95
+		g.lossless = true
+	}
+}
+
+// Sets the resolution images added afterwards are downsampled to if they would be placed at a higher one;
+// downsampled JPEGs are re-encoded. Zero or less embeds images at their full resolution (default: 0).
+func (g *Generator) SetMaxDPI(dpi float64) {
+	g.maxDPI = dpi
+}
+
+// Returns the options with the encoding chosen by the quality preset, unless they choose their own.
+func (g *Generator) withQuality(opts ImageOptions) ImageOptions {
+	if opts.JPEGQuality <= 0 {
+		opts.JPEGQuality = g.jpegQuality
+	}
+	if g.lossless && !opts.AssumeOpaque {
+		opts.AssumeAlpha = true
+	}
+	return opts
+}
+
+// Downsamples the image to the maximum resolution at its placement within area (in points), and returns
+// options laying it out at its original size. Images within the limit are left unchanged.
+func (g *Generator) limitDPI(p *pendingImage, area Rect, opts ImageOptions) (ImageOptions, error) {
+	if g.maxDPI <= 0 {
+		return opts, nil
+	}
+	size, ok, err := p.size()
+	if err != nil || !ok {
+		return opts, err
+	}
+	layoutSize := opts.layoutSize
+	if layoutSize == (image.Point{}) {
+		layoutSize = size
+	}
+	l := RenderLayoutInRect(area, Point, layoutSize.X, layoutSize.Y, opts)
+	d := opts.drawnRect(l.X, l.Y, l.W, l.H)
+	if d.W <= 0 || d.H <= 0 {
+		return opts, nil
+	}
+	dpi := max(float64(size.X)*72/d.W, float64(size.Y)*72/d.H)
+	if dpi <= g.maxDPI {
+		return opts, nil
+	}
+
+	img, err := g.decodePending(p)
+	if err != nil {
+		return opts, err
+	}
+	scale := g.maxDPI / dpi
+	p.replace(resize(img, max(int(math.Round(float64(size.X)*scale)), 1), max(int(math.Round(float64(size.Y)*scale)), 1)))
+	opts.layoutSize = layoutSize
+	return opts, nil
+}
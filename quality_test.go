@@ -0,0 +1,47 @@
+package p4p_test
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"regexp"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestQuality(t *testing.T) {
+	// About 240 DPI fitted to the width of an A4 page.
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1500))
+	rnd := rand.New(rand.NewSource(1))
+	for y := 0; y < 1500; y++ {
+		for x := 0; x < 2000; x++ {
+			// Noise on a gradient, like the grain of a photo.
+			img.Set(x, y, color.RGBA{R: uint8(x/8 + rnd.Intn(32)), G: uint8(y/6 + rnd.Intn(32)), B: uint8(rnd.Intn(32)), A: 0xff})
+		}
+	}
+	widthRe := regexp.MustCompile(`/Subtype /Image\n/Width (\d+)`)
+	sizes := make(map[p4p.Quality]int)
+	for _, tc := range []struct {
+		quality p4p.Quality
+		width   string
+	}{
+		{p4p.WebQuality, "1240"},
+		{p4p.PrintQuality, "2000"},
+		{p4p.ArchiveQuality, "2000"},
+	} {
+		g := p4p.NewGenerator(p4p.A4())
+		g.SetQuality(tc.quality)
+		if err := g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+		pdf := writePDF(t, g)
+		sizes[tc.quality] = len(pdf)
+		if m := widthRe.FindSubmatch(pdf); m == nil || string(m[1]) != tc.width {
+			t.Errorf("quality %d: expected the image %s pixels wide, got: %s", tc.quality, tc.width, m)
+		}
+	}
+	if sizes[p4p.WebQuality] >= sizes[p4p.PrintQuality] || sizes[p4p.WebQuality] >= sizes[p4p.ArchiveQuality] {
+		t.Fatal("expected the Web preset to produce the smallest file, got:", sizes)
+	}
+}
@@ -0,0 +1,68 @@
+package p4p
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	scaleBarThickness = 3
+	scaleBarFontSize  = 8
+	// Distance of the scale bar from the image edges and padding of its backdrop, in points.
+	scaleBarMargin = 8
+	scaleBarPad    = 3
+)
+
+// A scale bar drawn over an image, showing the physical length of a number of its pixels.
+type ScaleBarOptions struct {
+	// Image pixels per unit of length, e.g. 2 for pixels of 0.5 µm with µm as the unit; no bar is drawn if zero.
+	PixelsPerUnit float64
+	// Length of the bar in units (default: a round number of about a fifth of the image width).
+	Length float64
+	// Name of the unit, printed below the bar after its length, e.g. "µm".
+	Label string
+	// Position of the bar within the visible part of the image, e.g. Right and Bottom for the bottom-right
+	// corner (default: centered).
+	Position Align
+}
+
+// Returns a number of the form 1, 2 or 5 times a power of 10 close to, but not above, v.
+func roundLength(v float64) float64 {
+	p := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, f := range []float64{5, 2} {
+		if f*p <= v {
+			return f * p
+		}
+	}
+	return p
+}
+
+// Draws the scale bar of an image within rect (in points), the image being scaled to pxSize points per pixel.
+func (g *Generator) drawScaleBar(s ScaleBarOptions, rect Rect, imgWPx int, pxSize float64) {
+	length := s.Length
+	if length <= 0 {
+		length = roundLength(float64(imgWPx) / s.PixelsPerUnit / 5)
+	}
+	barW := length * s.PixelsPerUnit * pxSize
+	if g.tr == nil {
+		// Core fonts only support cp1252.
+		g.tr = g.pdf.UnicodeTranslatorFromDescriptor("")
+	}
+	text := g.tr(strings.TrimSpace(fmt.Sprintf("%g %s", length, s.Label)))
+	g.pdf.SetFont("Helvetica", "", scaleBarFontSize)
+	boxW := max(barW, g.pdf.GetStringWidth(text)) + 2*scaleBarPad
+	boxH := float64(scaleBarThickness + scaleBarFontSize + 3*scaleBarPad)
+
+	inner := rect.inset(Margins{Left: scaleBarMargin, Top: scaleBarMargin, Right: scaleBarMargin, Bottom: scaleBarMargin})
+	dx, dy := s.Position.offset(inner.W-boxW, inner.H-boxH)
+	x, y := inner.X+dx, inner.Y+dy
+
+	// A white backdrop keeps the bar readable on dark images.
+	g.pdf.SetFillColor(0xff, 0xff, 0xff)
+	g.pdf.Rect(x, y, boxW, boxH, "F")
+	g.pdf.SetFillColor(0, 0, 0)
+	g.pdf.Rect(x+(boxW-barW)/2, y+scaleBarPad, barW, scaleBarThickness, "F")
+	g.pdf.SetXY(x, y+scaleBarThickness+2*scaleBarPad)
+	g.pdf.CellFormat(boxW, scaleBarFontSize, text, "", 0, "CM", false, 0, "")
+}
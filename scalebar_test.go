@@ -0,0 +1,52 @@
+package p4p_test
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Returns the widths of the filled rectangles 3 points high, i.e. scale bars.
+func scaleBarWidths(content string) []float64 {
+	var widths []float64
+	for _, m := range regexp.MustCompile(`[-\d.]+ [-\d.]+ ([\d.]+) -3\.00 re f`).FindAllStringSubmatch(content, -1) {
+		w, _ := strconv.ParseFloat(m[1], 64)
+		widths = append(widths, w)
+	}
+	return widths
+}
+
+func TestScaleBar(t *testing.T) {
+	// gopher.png is 190 pixels wide.
+	for _, tc := range []struct {
+		mode   p4p.Mode
+		length float64
+		want   float64
+	}{
+		// 20 µm of 2 pixels each at 1 point per pixel.
+		{p4p.Center, 20, 40},
+		// A round number of units of about a fifth of the image: 10 µm.
+		{p4p.Center, 0, 20},
+		// Fit scales the 283 pixels high image to the page height.
+		{p4p.Fit, 20, 40 * 841.89 / 283},
+	} {
+		g := p4p.NewGenerator(p4p.A4())
+		opts := p4p.ImageOptions{Mode: tc.mode, ScaleBar: p4p.ScaleBarOptions{
+			PixelsPerUnit: 2,
+			Length:        tc.length,
+			Label:         "µm",
+			Position:      p4p.Align{H: p4p.Right, V: p4p.Bottom},
+		}}
+		if err := g.AddImageFile("gophers/gopher.png", opts); err != nil {
+			t.Fatal(err)
+		}
+		pages := pageContents(t, writePDF(t, g))
+		widths := scaleBarWidths(pages[0])
+		if len(widths) != 1 || math.Abs(widths[0]-tc.want) > 0.01 {
+			t.Errorf("mode %v, length %v: expected a %.2fpt scale bar, got: %v", tc.mode, tc.length, tc.want, widths)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package p4p
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Writes the document as several PDF files of up to pagesPerFile pages each, named by formatting
+// dirPattern with the number of the file counted from 1, e.g. "out/part-%03d.pdf".
+func (g *Generator) WriteFilesChunked(dirPattern string, pagesPerFile int) error {
+	if pagesPerFile < 1 {
+		return errors.New("p4p: pagesPerFile must be positive")
+	}
+	if g.PageCount() == 0 {
+		return ErrNoPage
+	}
+	var b bytes.Buffer
+	g.drawPageNumbers()
+	if err := g.pdf.Output(&b); err != nil {
+		return err
+	}
+	p, err := parsePDF(b.Bytes())
+	if err != nil {
+		return err
+	}
+	n := len(p.pages())
+	for i := 0; i*pagesPerFile < n; i++ {
+		chunk, err := p.subset(i*pagesPerFile+1, min(pagesPerFile, n-i*pagesPerFile))
+		if err != nil {
+			return err
+		}
+		g.amend(chunk)
+		if err := g.writeChunk(fmt.Sprintf(dirPattern, i+1), chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) writeChunk(path string, p *pdfFile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if g.objectStreams {
+		return p.writeCompact(f)
+	}
+	return p.write(f)
+}
+
+var (
+	countRe      = regexp.MustCompile(`/Count \d+`)
+	openActionRe = regexp.MustCompile(`/OpenAction \[\d+ 0 R`)
+	contentsRe   = regexp.MustCompile(`/Contents (\d+) 0 R`)
+	xobjectsRe   = regexp.MustCompile(`/XObject <<([^>]*)>>`)
+	xobjectRe    = regexp.MustCompile(`/(\S+) \d+ 0 R\n?`)
+	doRe         = regexp.MustCompile(`/(\S+) Do\b`)
+	// Link annotations as written by gofpdf.
+	linkRe = regexp.MustCompile(`<</Type /Annot /Subtype /Link /Rect \[[^\]]*\] /Border \[0 0 0\] /Dest \[(\d+) 0 R [^\]]*\]>>`)
+)
+
+// Returns a file with n pages starting at page first (counted from 1), keeping only the objects
+// these pages use. Links to other pages are removed.
+func (p *pdfFile) subset(first, n int) (*pdfFile, error) {
+	if bytes.Contains(p.trailer, []byte("/Encrypt")) {
+		// Strings are encrypted with the number of the object they are in.
+		return nil, errors.New("p4p: encrypted documents cannot be split")
+	}
+	pages := p.pages()
+	keep := pages[first-1 : first-1+n]
+	excluded := make(map[int]bool)
+	for _, page := range pages {
+		excluded[page] = true
+	}
+	for _, page := range keep {
+		delete(excluded, page)
+	}
+
+	objs := append([][]byte{}, p.objs...)
+	objs[1] = kidsRe.ReplaceAll(objs[1], []byte("/Kids ["+refs(keep)+"]"))
+	objs[1] = countRe.ReplaceAll(objs[1], []byte("/Count "+strconv.Itoa(n)))
+	if root := p.root(); root > 0 {
+		objs[root] = openActionRe.ReplaceAll(objs[root], []byte("/OpenAction ["+ref(keep[0])))
+	}
+	used := make(map[string]bool)
+	for _, page := range keep {
+		objs[page] = linkRe.ReplaceAllFunc(objs[page], func(link []byte) []byte {
+			dest, _ := strconv.Atoi(string(linkRe.FindSubmatch(link)[1]))
+			if excluded[dest] {
+				return nil
+			}
+			return link
+		})
+		for _, m := range contentsRe.FindAllSubmatch(objs[page], -1) {
+			c, _ := strconv.Atoi(string(m[1]))
+			if c <= 0 || c >= len(objs) {
+				return nil, errMalformedPDF
+			}
+			content, err := streamData(objs[c])
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range doRe.FindAllSubmatch(content, -1) {
+				used[string(m[1])] = true
+			}
+		}
+	}
+	// gofpdf shares one resource dictionary between all pages.
+	for i, obj := range objs {
+		if !bytes.Contains(obj, []byte("/ProcSet")) {
+			continue
+		}
+		objs[i] = xobjectsRe.ReplaceAllFunc(obj, func(dict []byte) []byte {
+			return xobjectRe.ReplaceAllFunc(dict, func(entry []byte) []byte {
+				if !used[string(xobjectRe.FindSubmatch(entry)[1])] {
+					return nil
+				}
+				return entry
+			})
+		})
+	}
+
+	// Renumber the objects reachable from the trailer, keeping the page tree root as object 1.
+	num := map[int]int{1: 1}
+	order := []int{1}
+	visit := func(b []byte) {
+		for _, m := range refRe.FindAllSubmatch(b, -1) {
+			r, _ := strconv.Atoi(string(m[1]))
+			if _, ok := num[r]; ok || excluded[r] || r <= 0 || r >= len(objs) {
+				continue
+			}
+			num[r] = len(order) + 1
+			order = append(order, r)
+		}
+	}
+	visit(p.trailer)
+	for i := 0; i < len(order); i++ {
+		dict, _ := splitStream(objs[order[i]])
+		visit(dict)
+	}
+	renumber := func(b []byte) []byte {
+		return refRe.ReplaceAllFunc(b, func(r []byte) []byte {
+			old, _ := strconv.Atoi(string(refRe.FindSubmatch(r)[1]))
+			if n, ok := num[old]; ok {
+				return []byte(ref(n))
+			}
+			return []byte("null")
+		})
+	}
+	q := &pdfFile{
+		header:    p.header,
+		objs:      make([][]byte, len(order)+1),
+		trailer:   renumber(p.trailer),
+		firstPage: first,
+	}
+	for i, old := range order {
+		dict, stream := splitStream(objs[old])
+		q.objs[i+1] = append(renumber(dict), stream...)
+	}
+	return q, nil
+}
+
+// Splits an object into its dictionary and its stream, if any.
+func splitStream(obj []byte) (dict, stream []byte) {
+	i := bytes.Index(obj, []byte("\nstream\n"))
+	if i < 0 {
+		return obj, nil
+	}
+	return obj[:i], obj[i:]
+}
+
+// Returns the decoded data of a stream object.
+func streamData(obj []byte) ([]byte, error) {
+	dict, stream := splitStream(obj)
+	end := bytes.LastIndex(stream, []byte("\nendstream"))
+	if end < 0 {
+		return nil, errMalformedPDF
+	}
+	data := stream[len("\nstream\n"):end]
+	if !bytes.Contains(dict, []byte("/FlateDecode")) {
+		return data, nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// Returns indirect references to the objects.
+func refs(objs []int) string {
+	r := make([]string, len(objs))
+	for i, n := range objs {
+		r[i] = ref(n)
+	}
+	return strings.Join(r, " ")
+}
@@ -0,0 +1,51 @@
+package p4p_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestWriteFilesChunked(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetNavigationButtons(p4p.NavOptions{})
+	for i := 0; i < 5; i++ {
+		// A distinct opaque image per page, embedded as a single JPEG object.
+		img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{Y: uint8(40 * i)}), image.Point{}, draw.Src)
+		if err := g.AddImage(img, p4p.ImageOptions{AltText: fmt.Sprint("Page ", i+1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	if err := g.WriteFilesChunked(filepath.Join(dir, "part-%d.pdf"), 2); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{2, 2, 1} {
+		pdf, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("part-%d.pdf", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages := pageObjects(t, pdf); len(pages) != want {
+			t.Fatal("expected", want, "pages in file", i+1, "got:", len(pages))
+		}
+		if images := bytes.Count(pdf, []byte("/Subtype /Image")); images != want {
+			t.Fatal("expected only the images of its pages in file", i+1, "got:", images)
+		}
+		for p := 1 + 2*i; p <= 2*i+want; p++ {
+			if !bytes.Contains(pdf, []byte(fmt.Sprintf("/Alt (Page %d)", p))) {
+				t.Fatal("missing figure of page", p, "in file", i+1)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "part-4.pdf")); !os.IsNotExist(err) {
+		t.Fatal("unexpected fourth file")
+	}
+}
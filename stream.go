@@ -0,0 +1,184 @@
+package p4p
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Builds a document page by page like Generator, but spills every page to a temporary file as soon as it
+// has been added, so that memory use is bounded by the largest single page rather than the whole document.
+// Each page is laid out by a Generator of its own, so settings spanning several pages are not available.
+// StreamingGenerators must be closed to remove the temporary file.
+type StreamingGenerator struct {
+	pageSize PageSize
+	tmp      *os.File
+	// Bytes written to tmp so far.
+	size int64
+	// Offsets of the objects in tmp, by object number; object 1 is the page tree root, written last.
+	offsets []int64
+	pages   []int
+	header  []byte
+}
+
+// Creates a StreamingGenerator spilling pages to a new temporary file in the default directory for
+// temporary files.
+func NewStreamingGenerator(pageSize PageSize) (*StreamingGenerator, error) {
+	tmp, err := os.CreateTemp("", "p4p_stream_")
+	if err != nil {
+		return nil, err
+	}
+	return &StreamingGenerator{
+		pageSize: pageSize.Convert(Point),
+		tmp:      tmp,
+		offsets:  []int64{0, 0},
+	}, nil
+}
+
+// Adds the image as a new page.
+func (s *StreamingGenerator) AddImage(img image.Image, opts ImageOptions) error {
+	return s.addPage(func(g *Generator) error { return g.AddImage(img, opts) })
+}
+
+// Adds the image file as a new page.
+func (s *StreamingGenerator) AddImageFile(path string, opts ImageOptions) error {
+	return s.addPage(func(g *Generator) error { return g.AddImageFile(path, opts) })
+}
+
+// Decodes an image in any registered format from r and adds it as a new page.
+func (s *StreamingGenerator) AddImageReader(r io.Reader, opts ImageOptions) error {
+	return s.addPage(func(g *Generator) error { return g.AddImageReader(r, opts) })
+}
+
+// Returns the number of pages added so far.
+func (s *StreamingGenerator) PageCount() int {
+	return len(s.pages)
+}
+
+// Lays out pages with a new Generator and appends them with all the objects they use to the temporary file.
+func (s *StreamingGenerator) addPage(add func(g *Generator) error) error {
+	g := NewGenerator(s.pageSize)
+	if err := add(g); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := g.Write(&b); err != nil {
+		return err
+	}
+	p, err := parsePDF(b.Bytes())
+	if err != nil {
+		return err
+	}
+	if bytes.Compare(p.header, s.header) > 0 {
+		// The highest PDF version any page needs.
+		s.header = p.header
+	}
+
+	// Renumber the objects reachable from the pages, following the objects written before. The page
+	// tree root stays object 1.
+	num := map[int]int{1: 1}
+	var order []int
+	visit := func(b []byte) {
+		for _, m := range refRe.FindAllSubmatch(b, -1) {
+			r, _ := strconv.Atoi(string(m[1]))
+			if _, ok := num[r]; ok || r <= 0 || r >= len(p.objs) {
+				continue
+			}
+			num[r] = len(s.offsets) + len(order)
+			order = append(order, r)
+		}
+	}
+	pages := p.pages()
+	for _, page := range pages {
+		visit([]byte(ref(page)))
+	}
+	for i := 0; i < len(order); i++ {
+		dict, _ := splitStream(p.objs[order[i]])
+		visit(dict)
+	}
+	renumber := func(b []byte) []byte {
+		return refRe.ReplaceAllFunc(b, func(r []byte) []byte {
+			old, _ := strconv.Atoi(string(refRe.FindSubmatch(r)[1]))
+			if n, ok := num[old]; ok {
+				return []byte(ref(n))
+			}
+			return []byte("null")
+		})
+	}
+
+	w := bufio.NewWriter(s.tmp)
+	for _, old := range order {
+		s.offsets = append(s.offsets, s.size)
+		dict, stream := splitStream(p.objs[old])
+		n, err := fmt.Fprintf(w, "%d 0 obj\n%s%sendobj\n", num[old], renumber(dict), stream)
+		if err != nil {
+			return err
+		}
+		s.size += int64(n)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, page := range pages {
+		s.pages = append(s.pages, num[page])
+	}
+	return nil
+}
+
+// Writes the document with all pages added so far to w. Returns ErrNoPage, writing nothing, if no page was added.
+func (s *StreamingGenerator) Write(w io.Writer) error {
+	if len(s.pages) == 0 {
+		return ErrNoPage
+	}
+	bw := bufio.NewWriter(w)
+	bw.Write(s.header)
+	base := int64(len(s.header))
+	if _, err := io.Copy(bw, io.NewSectionReader(s.tmp, 0, s.size)); err != nil {
+		return err
+	}
+
+	offsets := append([]int64{}, s.offsets...)
+	pos := base + s.size
+	putObj := func(n int, obj string) {
+		for len(offsets) <= n {
+			offsets = append(offsets, 0)
+		}
+		offsets[n] = pos - base
+		c, _ := fmt.Fprintf(bw, "%d 0 obj\n%s\nendobj\n", n, obj)
+		pos += int64(c)
+	}
+	putObj(1, fmt.Sprintf("<</Type /Pages\n/Kids [%s]\n/Count %d\n/MediaBox [0 0 %.2f %.2f]\n>>",
+		refs(s.pages), len(s.pages), s.pageSize.W, s.pageSize.H))
+	catalog := len(offsets)
+	putObj(catalog, "<</Type /Catalog\n/Pages 1 0 R\n>>")
+
+	fmt.Fprintf(bw, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(bw, "%010d 00000 n \n", base+off)
+	}
+	fmt.Fprintf(bw, "trailer\n<<\n/Size %d\n/Root %s\n>>\nstartxref\n%d\n%%%%EOF\n", len(offsets), ref(catalog), pos)
+	return bw.Flush()
+}
+
+// Writes the document to the file at path. Returns ErrNoPage, without creating the file, if no page was added.
+func (s *StreamingGenerator) WriteFile(path string) error {
+	if len(s.pages) == 0 {
+		return ErrNoPage
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.Write(f)
+}
+
+// Removes the temporary file. The StreamingGenerator must not be used afterwards.
+func (s *StreamingGenerator) Close() error {
+	s.tmp.Close()
+	return os.Remove(s.tmp.Name())
+}
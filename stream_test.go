@@ -0,0 +1,100 @@
+package p4p_test
+
+import (
+	"bytes"
+	"image"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestStreamingGenerator(t *testing.T) {
+	s, err := p4p.NewStreamingGenerator(p4p.A4())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Write(&bytes.Buffer{}); err != p4p.ErrNoPage {
+		t.Fatal("expected ErrNoPage, got:", err)
+	}
+	paths := []string{"gophers/gopher.png", "gophers/gopher1.jpg", "gophers/gopher2.png"}
+	for _, path := range paths {
+		if err := s.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fit, Caption: path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AddImage(decodeFile(t, "gophers/gopher.png"), p4p.ImageOptions{Mode: p4p.Fill}); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.PageCount(); n != 4 {
+		t.Fatal("expected 4 pages, got:", n)
+	}
+
+	var b bytes.Buffer
+	if err := s.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, b.Bytes())
+	if len(pages) != 4 {
+		t.Fatal("expected 4 pages, got:", len(pages))
+	}
+	for i, page := range pages {
+		if len(imageDraws(page)) != 1 {
+			t.Errorf("expected an image on page %d, got: %q", i+1, page)
+		}
+	}
+	paths2, err := p4p.ExtractImages(bytes.NewReader(b.Bytes()), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths2) != 4 {
+		t.Fatal("expected 4 images, got:", len(paths2))
+	}
+}
+
+// Adds 200 different images of about 500KB each, returning the peak heap in use while adding them.
+func addImagesPeakHeap(b *testing.B, add func(img image.Image) error) uint64 {
+	img := image.NewGray(image.Rect(0, 0, 1000, 500))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	var peak uint64
+	var stats runtime.MemStats
+	for i := 0; i < 200; i++ {
+		img.Pix[0] = byte(i)
+		if err := add(img); err != nil {
+			b.Fatal(err)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		peak = max(peak, stats.HeapInuse)
+	}
+	return peak
+}
+
+func BenchmarkGeneratorMemory(b *testing.B) {
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		g := p4p.NewGenerator(p4p.A4())
+		peak = max(peak, addImagesPeakHeap(b, func(img image.Image) error {
+			return g.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit, AssumeAlpha: true})
+		}))
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+}
+
+// Compare to BenchmarkGeneratorMemory: the peak stays at the size of a few images.
+func BenchmarkStreamingGeneratorMemory(b *testing.B) {
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		s, err := p4p.NewStreamingGenerator(p4p.A4())
+		if err != nil {
+			b.Fatal(err)
+		}
+		peak = max(peak, addImagesPeakHeap(b, func(img image.Image) error {
+			return s.AddImage(img, p4p.ImageOptions{Mode: p4p.Fit, AssumeAlpha: true})
+		}))
+		s.Close()
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+}
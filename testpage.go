@@ -0,0 +1,131 @@
+package p4p
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+)
+
+// Ruler tick spacing for a unit.
+type rulerScale struct {
+	name string
+	// Distance between labeled ticks, in points.
+	major float64
+	// Number of ticks per labeled tick.
+	minor int
+}
+
+func rulerScaleOf(unit Unit) rulerScale {
+	switch unit {
+	case Point:
+		return rulerScale{"pt", 72, 8}
+	case Millimeter:
+		return rulerScale{"mm", float64(10 * Millimeter), 10}
+	case Centimeter:
+		return rulerScale{"cm", float64(Centimeter), 10}
+	case Inch:
+		return rulerScale{"in", float64(Inch), 8}
+	}
+	return rulerScale{"units", float64(unit), 1}
+}
+
+// Distance of the dashed frame and the registration marks from the paper edge, in points.
+const testPageInset = 36
+
+// Adds a calibration page for checking printer scaling before a print run: rulers measuring from the
+// top left corner of the paper with ticks labeled in the given unit, a dashed margin frame,
+// registration marks, color swatches and sample text.
+func (g *Generator) AddTestPage(unit Unit) error {
+	if unit <= 0 {
+		return errors.New("p4p: unit must be positive")
+	}
+	s := rulerScaleOf(unit)
+	g.addPage()
+	pdf := g.pdf
+	w, h := pdf.GetPageSize()
+	lineWidth := pdf.GetLineWidth()
+	defer pdf.SetLineWidth(lineWidth)
+
+	// Grid at every labeled tick.
+	pdf.SetLineWidth(0.25)
+	setDrawColor(pdf, g.color(color.Gray{Y: 0xcc}))
+	for x := s.major; x < w; x += s.major {
+		pdf.Line(x, 0, x, h)
+	}
+	for y := s.major; y < h; y += s.major {
+		pdf.Line(0, y, w, y)
+	}
+
+	setDrawColor(pdf, g.color(color.Black))
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "", 6)
+	step := s.major / float64(s.minor)
+	tick := func(i int) float64 {
+		switch {
+		case i%s.minor == 0:
+			return 12
+		case s.minor%2 == 0 && i%(s.minor/2) == 0:
+			return 8
+		}
+		return 4
+	}
+	label := func(i int) string {
+		return strconv.FormatFloat(float64(i/s.minor)*s.major/float64(unit), 'g', 4, 64)
+	}
+	for i := 1; float64(i)*step < w; i++ {
+		x := float64(i) * step
+		pdf.Line(x, 0, x, tick(i))
+		if i%s.minor == 0 {
+			pdf.Text(x+1, 18, label(i))
+		}
+	}
+	for i := 1; float64(i)*step < h; i++ {
+		y := float64(i) * step
+		pdf.Line(0, y, tick(i), y)
+		if i%s.minor == 0 {
+			pdf.Text(14, y-1, label(i))
+		}
+	}
+
+	// Margin frame and registration marks.
+	pdf.SetDashPattern([]float64{3, 3}, 0)
+	pdf.Rect(testPageInset, testPageInset, w-2*testPageInset, h-2*testPageInset, "D")
+	pdf.SetDashPattern(nil, 0)
+	for _, p := range [][2]float64{
+		{testPageInset, testPageInset}, {w - testPageInset, testPageInset},
+		{testPageInset, h - testPageInset}, {w - testPageInset, h - testPageInset},
+		{w / 2, h / 2},
+	} {
+		pdf.Circle(p[0], p[1], 6, "D")
+		pdf.Line(p[0]-10, p[1], p[0]+10, p[1])
+		pdf.Line(p[0], p[1]-10, p[0], p[1]+10)
+	}
+
+	// Color swatches and a gray ramp.
+	const swatch = 24
+	x0, y := w/2-5.5*swatch, h/2+40
+	for i, c := range []color.Color{
+		color.CMYK{C: 0xff}, color.CMYK{M: 0xff}, color.CMYK{Y: 0xff}, color.CMYK{K: 0xff},
+		color.RGBA{R: 0xff, A: 0xff}, color.RGBA{G: 0xff, A: 0xff}, color.RGBA{B: 0xff, A: 0xff},
+	} {
+		setFillColor(pdf, g.color(c))
+		pdf.Rect(x0+float64(i)*swatch, y, swatch, swatch, "F")
+	}
+	for i := 0; i <= 10; i++ {
+		setFillColor(pdf, g.color(color.Gray{Y: uint8(0xff * (10 - i) / 10)}))
+		pdf.Rect(x0+float64(i)*swatch, y+swatch, swatch, swatch, "FD")
+	}
+
+	pdf.SetFont("Helvetica", "", 10)
+	inset := strconv.FormatFloat(testPageInset/float64(unit), 'g', 3, 64)
+	for i, line := range []string{
+		fmt.Sprintf("Print test page, rulers in %s", s.name),
+		"Print at 100% (actual size). The rulers measure from the top left corner of the paper.",
+		fmt.Sprintf("The dashed frame is %s %s from the paper edge.", inset, s.name),
+		"Sample text: The quick brown fox jumps over the lazy dog. 0123456789",
+	} {
+		pdf.Text(testPageInset+24, y+2*swatch+20+float64(i)*14, line)
+	}
+	return pdf.Error()
+}
@@ -0,0 +1,42 @@
+package p4p_test
+
+import (
+	"strings"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddTestPage(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	for _, unit := range []p4p.Unit{p4p.Centimeter, p4p.Inch} {
+		if err := g.AddTestPage(unit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AddTestPage(0); err == nil {
+		t.Fatal("expected an error for an invalid unit")
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 test pages, got:", len(pages))
+	}
+	// A4 is 21 x 29.7 cm: a tick every millimeter, plus grid lines every centimeter.
+	if lines := strings.Count(pages[0], " l S"); lines < 209+296+20+29 {
+		t.Fatal("expected ruler ticks and grid lines, got:", lines)
+	}
+	for _, label := range []string{"(1) Tj", "(20) Tj", "(29) Tj", "rulers in cm", "(8) Tj"} {
+		if !strings.Contains(pages[0], label) {
+			t.Fatal("missing centimeter label", label)
+		}
+	}
+	// 8.27 x 11.69 in, labeled every inch.
+	for _, label := range []string{"(1) Tj", "(8) Tj", "(11) Tj", "rulers in in", "0.5 in from the paper edge"} {
+		if !strings.Contains(pages[1], label) {
+			t.Fatal("missing inch label", label)
+		}
+	}
+	if strings.Contains(pages[1], "(12) Tj") {
+		t.Fatal("inch ruler runs past the paper")
+	}
+}
@@ -0,0 +1,60 @@
+package p4p
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Space around and between thumbnails, in points.
+const thumbnailGutter = 12
+
+// Appends pages with a grid of thumbnails in cols columns, one for each page added so far, each linking
+// to its page and labeled with its page number. A page's thumbnail shows the first image placed on it,
+// pages without images get an empty frame.
+func (g *Generator) AppendThumbnailIndex(cols int) error {
+	if cols < 1 {
+		return errors.New("p4p: cols must be at least 1")
+	}
+	n := g.pdf.PageNo()
+	if n == 0 {
+		return nil
+	}
+	images := make(map[int]IndexEntry)
+	for _, e := range g.index {
+		if _, ok := images[e.Page]; !ok {
+			images[e.Page] = e
+		}
+	}
+
+	area := g.pageRect()
+	area = Rect{X: area.X + thumbnailGutter, Y: area.Y + thumbnailGutter, W: area.W - 2*thumbnailGutter, H: area.H - 2*thumbnailGutter}
+	cellW := (area.W - thumbnailGutter*float64(cols-1)) / float64(cols)
+	cellH := cellW + g.captionHeight()
+	rows := max(int((area.H+thumbnailGutter)/(cellH+thumbnailGutter)), 1)
+	grid := Grid{Rect: area, Columns: cols, Rows: rows, Gutter: thumbnailGutter}
+
+	perPage := cols * rows
+	for page := 1; page <= n; page++ {
+		i := (page - 1) % perPage
+		if i == 0 {
+			g.addPage()
+		}
+		cell := grid.Cell(i%cols, i/cols, 1, 1)
+		thumb := Rect{X: cell.X, Y: cell.Y, W: cell.W, H: cell.H - g.captionHeight()}
+		if e, ok := images[page]; ok {
+			info := g.pdf.GetImageInfo(e.name)
+			l := RenderLayoutInRect(thumb, Point, int(info.Width()), int(info.Height()), ImageOptions{Mode: Fit})
+			g.pdf.ImageOptions(e.name, l.X, l.Y, l.W, l.H, false, gofpdf.ImageOptions{AllowNegativePosition: true}, 0, "")
+		} else {
+			g.pdf.SetDrawColor(0x80, 0x80, 0x80)
+			g.pdf.Rect(thumb.X, thumb.Y, thumb.W, thumb.H, "D")
+		}
+		g.drawCaption(strconv.Itoa(page), Rect{X: cell.X, Y: thumb.Y + thumb.H, W: cell.W, H: g.captionHeight()})
+		link := g.pdf.AddLink()
+		g.pdf.SetLink(link, 0, page)
+		g.pdf.Link(cell.X, cell.Y, cell.W, cell.H, link)
+	}
+	return g.pdf.Error()
+}
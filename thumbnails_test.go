@@ -0,0 +1,68 @@
+package p4p_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAppendThumbnailIndex(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	paths := []string{"gophers/gopher.png", "gophers/gopher1.jpg", "gophers/gopher2.png"}
+	for _, path := range paths {
+		if err := g.AddImageFile(path, p4p.ImageOptions{Mode: p4p.Fill}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AppendThumbnailIndex(2); err != nil {
+		t.Fatal(err)
+	}
+	pdf := writePDF(t, g)
+	pages := pageContents(t, pdf)
+	if len(pages) != len(paths)+1 {
+		t.Fatal("expected a single index page, got pages:", len(pages))
+	}
+	if n := len(imageDraws(pages[len(paths)])); n != len(paths) {
+		t.Fatal("expected a thumbnail per page, got:", n)
+	}
+
+	// Page objects in page order, and the pages the index links to.
+	objs := regexp.MustCompile(`(\d+) 0 obj\n<</Type /Page\b`).FindAllSubmatch(pdf, -1)
+	if len(objs) != len(pages) {
+		t.Fatal("expected a page object per page, got:", len(objs))
+	}
+	index := pdf[bytes.Index(pdf, objs[len(paths)][0]):]
+	index = index[:bytes.Index(index, []byte("endobj"))]
+	dests := regexp.MustCompile(`/Subtype /Link .*?/Dest \[(\d+) 0 R`).FindAllSubmatch(index, -1)
+	if len(dests) != len(paths) {
+		t.Fatal("expected a link per page, got:", len(dests))
+	}
+	for i, d := range dests {
+		if want := string(objs[i][1]); string(d[1]) != want {
+			t.Errorf("link %d: expected page object %s, got %s", i, want, d[1])
+		}
+	}
+}
+
+func TestAppendThumbnailIndexPaging(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	img := decodeFile(t, "gophers/gopher.png")
+	for i := 0; i < 30; i++ {
+		if err := g.AddImage(img, p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AppendThumbnailIndex(4); err != nil {
+		t.Fatal(err)
+	}
+	pages := pageContents(t, writePDF(t, g))
+	thumbs := 0
+	for _, page := range pages[30:] {
+		thumbs += len(imageDraws(page))
+	}
+	if len(pages) <= 31 || thumbs != 30 {
+		t.Fatal("expected 30 thumbnails spread over several index pages, got:", len(pages)-30, thumbs)
+	}
+}
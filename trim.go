@@ -0,0 +1,72 @@
+package p4p
+
+import (
+	"image"
+)
+
+// Largest difference of a color channel (out of 0xffff) from the border color still counted as border,
+// allowing for scanner noise and compression artifacts.
+const trimTolerance = 0x1000
+
+// Returns the bounds of the image without the border of uniform color around it, the color of its
+// top-left pixel. Images consisting only of border are returned whole.
+func trimmedBounds(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	if b.Empty() {
+		return b
+	}
+	br, bg, bb, ba := img.At(b.Min.X, b.Min.Y).RGBA()
+	isBorder := func(x, y int) bool {
+		r, g, b, a := img.At(x, y).RGBA()
+		return absDiff(r, br) <= trimTolerance && absDiff(g, bg) <= trimTolerance &&
+			absDiff(b, bb) <= trimTolerance && absDiff(a, ba) <= trimTolerance
+	}
+	content := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !isBorder(x, y) {
+				content = content.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if content.Empty() {
+		return b
+	}
+	return content
+}
+
+// Part of a slice at its bottom searched for rows of uniform color to split at.
+const splitSearchFraction = 0.2
+
+// Returns where to end the slice of the image from row y0 to end, exclusive, so that it ends right below the
+// row of uniform color closest to end within its bottom part, or end if there is none.
+func whitespaceSplit(img image.Image, y0, end int) int {
+	b := img.Bounds()
+	low := max(end-int(float64(end-y0)*splitSearchFraction), y0+1)
+	for y := end - 1; y >= low; y-- {
+		if uniformRow(img, y, b.Min.X, b.Max.X) {
+			return y + 1
+		}
+	}
+	return end
+}
+
+// Reports whether the pixels from x0 to x1 of row y are of the same color as its first, within trimTolerance.
+func uniformRow(img image.Image, y, x0, x1 int) bool {
+	br, bg, bb, ba := img.At(x0, y).RGBA()
+	for x := x0 + 1; x < x1; x++ {
+		r, g, b, a := img.At(x, y).RGBA()
+		if absDiff(r, br) > trimTolerance || absDiff(g, bg) > trimTolerance ||
+			absDiff(b, bb) > trimTolerance || absDiff(a, ba) > trimTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
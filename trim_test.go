@@ -0,0 +1,55 @@
+package p4p_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestTrimBorder(t *testing.T) {
+	// A scan with 200x200 pixels of content off-center on a white page.
+	scan := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	draw.Draw(scan, scan.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(scan, image.Rect(150, 60, 350, 260), image.NewUniform(color.RGBA{0x20, 0x40, 0x60, 0xff}), image.Point{}, draw.Src)
+	// Scanner noise in the border.
+	scan.Set(10, 10, color.RGBA{0xfa, 0xfa, 0xfa, 0xff})
+	path := filepath.Join(t.TempDir(), "scan.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, scan); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	g := p4p.NewGenerator(p4p.PageSize{W: 400, H: 600, Unit: p4p.Point})
+	opts := p4p.ImageOptions{Mode: p4p.Fit, TrimBorder: true, Margins: p4p.Margins{Top: 20, Right: 20, Bottom: 20, Left: 20}}
+	if err := g.AddImageFile(path, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImage(scan, opts); err != nil {
+		t.Fatal(err)
+	}
+	// The square content fits the 360 points wide content box, centered.
+	want := p4p.Rect{X: 20, Y: 120, W: 360, H: 360}
+	for i, page := range pageContents(t, writePDF(t, g)) {
+		draws := imageDraws(page)
+		if len(draws) != 1 {
+			t.Fatalf("page %d: expected one image, got: %v", i+1, draws)
+		}
+		// PDF coordinates start at the bottom.
+		d := draws[0]
+		got := p4p.Rect{X: d.X, Y: 600 - d.Y - d.H, W: d.W, H: d.H}
+		if math.Abs(got.X-want.X) > 0.01 || math.Abs(got.Y-want.Y) > 0.01 || math.Abs(got.W-want.W) > 0.01 || math.Abs(got.H-want.H) > 0.01 {
+			t.Errorf("page %d: expected the trimmed content at %+v, got %+v", i+1, want, got)
+		}
+	}
+}
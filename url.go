@@ -0,0 +1,63 @@
+package p4p
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// Used by AddImageURL unless a client is set with SetHTTPClient.
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Sets the client used to fetch images in AddImageURL; use the client's Timeout to limit how long a fetch may take.
+// Passing nil restores the default client, which times out after 30 seconds.
+func (g *Generator) SetHTTPClient(c *http.Client) {
+	g.httpClient = c
+}
+
+// Fetches an image via HTTP(S) and adds it as a new page.
+// The format is detected from the response's Content-Type, falling back to sniffing the data.
+// Images are turned upright according to their EXIF orientation.
+func (g *Generator) AddImageURL(url string, opts ImageOptions) error {
+	client := g.httpClient
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("p4p: fetching %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	g.checkColorProfile(url, data)
+	defer g.recordGeoLocation(g.pdf.PageNo(), data)
+	opts.source = url
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType = http.DetectContentType(data)
+	}
+	switch mediaType {
+	case "image/jpeg", "image/png", "image/gif":
+		if !opts.needsDecoding() && exifOrientation(data) == 1 {
+			// Natively supported, embed without re-encoding.
+			return g.addImage(mediaType[len("image/"):], bytes.NewReader(data), opts)
+		}
+	}
+	// Any other format with a registered decoder.
+	img, err := g.decodeOriented(data)
+	if err != nil {
+		return fmt.Errorf("p4p: decoding %s (%s): %w", url, mediaType, err)
+	}
+	return g.AddImage(img, opts)
+}
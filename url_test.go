@@ -0,0 +1,50 @@
+package p4p_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddImageURL(t *testing.T) {
+	data, err := os.ReadFile("gophers/gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gopher.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(data)
+		case "/untyped":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetHTTPClient(srv.Client())
+	for _, path := range []string{"/gopher.png", "/untyped"} {
+		if err := g.AddImageURL(srv.URL+path, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.AddImageURL(srv.URL+"/missing.png", p4p.ImageOptions{}); err == nil {
+		t.Fatal("expected error for 404 response")
+	}
+	pages := pageContents(t, writePDF(t, g))
+	if len(pages) != 2 {
+		t.Fatal("expected 2 pages, got:", len(pages))
+	}
+	for _, page := range pages {
+		if len(imageDraws(page)) != 1 {
+			t.Fatal("expected an image on every page")
+		}
+	}
+}
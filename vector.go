@@ -0,0 +1,33 @@
+package p4p
+
+import (
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf/contrib/gofpdi"
+)
+
+// Draws a page of a PDF file onto the current page as a vector Form XObject, e.g. for crisp logos.
+// The page, numbered from 1, is scaled to fit and centered within rect (in points).
+func (g *Generator) AddVectorOverlay(pdfPath string, page int, rect Rect) (err error) {
+	if g.pdf.PageNo() == 0 {
+		return ErrNoPage
+	}
+	if g.importer == nil {
+		g.importer = gofpdi.NewImporter()
+	}
+	// gofpdi panics on unreadable files and missing pages.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("p4p: importing %s page %d: %v", pdfPath, page, r)
+		}
+	}()
+	tpl := g.importer.ImportPage(g.pdf, pdfPath, page, "/MediaBox")
+	box := g.importer.GetPageSizes()[page]["/MediaBox"]
+	if box["w"] <= 0 || box["h"] <= 0 {
+		return fmt.Errorf("p4p: importing %s page %d: empty media box", pdfPath, page)
+	}
+	scale := min(rect.W/box["w"], rect.H/box["h"])
+	w, h := box["w"]*scale, box["h"]*scale
+	g.importer.UseImportedTemplate(g.pdf, tpl, rect.X+(rect.W-w)/2, rect.Y+(rect.H-h)/2, w, h)
+	return g.pdf.Error()
+}
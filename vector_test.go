@@ -0,0 +1,57 @@
+package p4p_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+// Writes a 200x100pt PDF with a filled rectangle.
+func vectorPDF(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "logo.pdf")
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "pt", Size: gofpdf.SizeType{Wd: 200, Ht: 100}})
+	pdf.AddPage()
+	pdf.SetFillColor(0x20, 0x40, 0x80)
+	pdf.Rect(10, 10, 180, 80, "F")
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAddVectorOverlay(t *testing.T) {
+	logo := vectorPDF(t)
+	g := p4p.NewGenerator(p4p.A4())
+	// Amending the output must cope with the imported objects.
+	g.SetLanguage("en")
+	if err := g.AddVectorOverlay(logo, 1, p4p.Rect{W: 100, H: 100}); err != p4p.ErrNoPage {
+		t.Fatal("expected ErrNoPage, got:", err)
+	}
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddVectorOverlay(logo, 1, p4p.Rect{X: 20, Y: 20, W: 100, H: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddVectorOverlay(logo, 2, p4p.Rect{W: 100, H: 100}); err == nil {
+		t.Fatal("expected an error importing a missing page")
+	}
+	pdf := writePDF(t, g)
+	if !bytes.Contains(pdf, []byte("/Subtype /Form")) {
+		t.Fatal("missing Form XObject")
+	}
+	page := pageContents(t, pdf)[0]
+	// Scaled by half to fit the width, centered vertically in the rect.
+	m := regexp.MustCompile(`q ([\d.]+) 0 0 ([\d.]+) ([\d.]+) ([\d.]+) cm /GOFPDITPL\d+ Do Q`).FindStringSubmatch(page)
+	if m == nil {
+		t.Fatal("Form XObject not placed, got:", page)
+	}
+	if got := strings.Join(m[1:], " "); got != "0.5000 0.5000 20.0000 746.8900" {
+		t.Fatal("wrong placement, got:", got)
+	}
+}
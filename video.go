@@ -0,0 +1,58 @@
+package p4p
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Returned when a feature depends on ffmpeg, but no ffmpeg executable is in PATH.
+var ErrFFmpegNotFound = errors.New("p4p: ffmpeg not found in PATH (required to read video files)")
+
+// Adds every nth frame (starting with the first) of the video at path as a separate page.
+// Depends on an ffmpeg executable in PATH to extract the frames.
+func (g *Generator) AddVideoFrames(path string, everyN int, opts ImageOptions) error {
+	if everyN < 1 {
+		return errors.New("p4p: everyN must be at least 1")
+	}
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return ErrFFmpegNotFound
+	}
+
+	dir, err := os.MkdirTemp("", "p4p_frames_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(ffmpeg,
+		"-loglevel", "error",
+		"-i", path,
+		"-vf", fmt.Sprintf(`select=not(mod(n\,%d))`, everyN),
+		"-vsync", "vfr",
+		filepath.Join(dir, "%08d.png"),
+	)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("p4p: ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// Frame files are zero-padded, so lexical order is frame order.
+	frames, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		return err
+	}
+	for i, frame := range frames {
+		opts.source = fmt.Sprintf("%s#%d", path, i*everyN)
+		if err := g.AddImageFile(frame, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package p4p_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestAddVideoFrames(t *testing.T) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	// 10 frames.
+	video := filepath.Join(t.TempDir(), "video.mp4")
+	if out, err := exec.Command(ffmpeg, "-loglevel", "error", "-f", "lavfi", "-i", "testsrc=duration=1:size=64x48:rate=10", video).CombinedOutput(); err != nil {
+		t.Fatal(err, string(out))
+	}
+
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddVideoFrames(video, 3, p4p.ImageOptions{Mode: p4p.Fit}); err != nil {
+		t.Fatal(err)
+	}
+	// Frames 0, 3, 6, 9.
+	if n := len(pageContents(t, writePDF(t, g))); n != 4 {
+		t.Fatal("expected 4 pages, got:", n)
+	}
+}
+
+func TestAddVideoFramesMissingFFmpeg(t *testing.T) {
+	t.Setenv("PATH", "")
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.AddVideoFrames("video.mp4", 1, p4p.ImageOptions{}); err != p4p.ErrFFmpegNotFound {
+		t.Fatal("expected ErrFFmpegNotFound, got:", err)
+	}
+}
@@ -0,0 +1,33 @@
+package p4p
+
+import (
+	"fmt"
+)
+
+// Sets the reading order of pages, "L2R" (default) or "R2L" for right-to-left documents such as manga.
+// Viewers use it to order pages when displaying them side by side.
+func (g *Generator) SetPageProgression(dir string) error {
+	switch dir {
+	case "L2R", "R2L":
+		g.pageProgression = dir
+		return nil
+	}
+	return fmt.Errorf("p4p: invalid page progression %q, must be L2R or R2L", dir)
+}
+
+// Sets whether the document starts on a right-hand page like a book (default: false). A blank page is
+// inserted before the first page, and viewers show the pages as two-page spreads, even pages on the left.
+func (g *Generator) SetStartOnRight(enabled bool) {
+	g.startOnRight = enabled
+	if enabled {
+		g.pdf.SetDisplayMode("default", "TwoPageLeft")
+	} else {
+		g.pdf.SetDisplayMode("default", "default")
+	}
+}
+
+func (g *Generator) putViewerPreferences(p *pdfFile) {
+	if g.pageProgression != "" {
+		p.extendDict(p.root(), "/ViewerPreferences <</Direction /"+g.pageProgression+">>")
+	}
+}
@@ -0,0 +1,46 @@
+package p4p_test
+
+import (
+	"bytes"
+	"testing"
+
+	p4p "github.com/pic4pdf/lib-p4p"
+)
+
+func TestPageProgression(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	if err := g.SetPageProgression("up"); err == nil {
+		t.Fatal("expected error for invalid direction")
+	}
+	if err := g.SetPageProgression("R2L"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddImageFile("gophers/gopher.png", p4p.ImageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(writePDF(t, g), []byte("/ViewerPreferences <</Direction /R2L>>")) {
+		t.Fatal("output is missing page progression")
+	}
+}
+
+func TestStartOnRight(t *testing.T) {
+	g := p4p.NewGenerator(p4p.A4())
+	g.SetStartOnRight(true)
+	for _, path := range []string{"gophers/gopher.png", "gophers/gopher1.jpg"} {
+		if err := g.AddImageFile(path, p4p.ImageOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pdf := writePDF(t, g)
+	if !bytes.Contains(pdf, []byte("/PageLayout /TwoPageLeft")) {
+		t.Fatal("output is missing two-page layout")
+	}
+	pages := pageContents(t, pdf)
+	if len(pages) != 3 {
+		t.Fatal("expected a blank page and 2 image pages, got", len(pages))
+	}
+	// The first spread is the blank left page facing the first image.
+	if len(imageDraws(pages[0])) != 0 || len(imageDraws(pages[1])) != 1 {
+		t.Fatalf("expected blank left page facing the first image, got %q and %q", pages[0], pages[1])
+	}
+}